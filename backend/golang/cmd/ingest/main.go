@@ -0,0 +1,251 @@
+// Command ingest bulk loads quotes from the JSON or NDJSON files used by
+// data-ingestion/ into the quotes table, upserting on (quote, author).
+//
+// Usage:
+//
+//	go run ./cmd/ingest [-batch-size 1000] [-retries 3] [-dry-run] FILE...
+//
+// A FILE of "-" reads from standard input.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/joho/godotenv"
+	"quotes-api/queries"
+)
+
+// rejection records why an input record was not ingested
+type rejection struct {
+	file   string
+	index  int
+	reason string
+}
+
+// staged is a validated record waiting for its batch
+type staged struct {
+	index int
+	input queries.QuoteInput
+}
+
+// report accumulates the outcome of an ingestion run
+type report struct {
+	queries.IngestResult
+	rejected []rejection
+}
+
+// upserter merges a batch into quotes, or into a dry run
+type upserter interface {
+	UpsertBatch(ctx context.Context, batch []queries.QuoteInput) (queries.IngestResult, error)
+}
+
+type ingester struct {
+	ingest    upserter
+	batchSize int
+	retries   int
+	dryRun    bool
+	report    report
+}
+
+func main() {
+	batchSize := flag.Int("batch-size", 1000, "quotes per staging batch")
+	retries := flag.Int("retries", 3, "retries per batch on transient errors")
+	dryRun := flag.Bool("dry-run", false, "validate and merge every batch inside one transaction that is rolled back")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] FILE...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 || *batchSize < 1 || *retries < 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// Load environment variables from .env file
+	if err := godotenv.Load(); err != nil {
+		log.Printf("Warning: Could not load .env file: %v", err)
+	}
+
+	databaseURL := os.Getenv("DATABASE_URL")
+	if databaseURL == "" {
+		log.Fatal("DATABASE_URL environment variable is required")
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, databaseURL)
+	if err != nil {
+		log.Fatalf("Failed to create connection pool: %v", err)
+	}
+	defer pool.Close()
+
+	if err := pool.Ping(ctx); err != nil {
+		log.Fatalf("Failed to ping database: %v", err)
+	}
+
+	ingestQueries := queries.NewIngestQueries(pool)
+	in := &ingester{
+		ingest:    ingestQueries,
+		batchSize: *batchSize,
+		retries:   *retries,
+		dryRun:    *dryRun,
+	}
+	if *dryRun {
+		dry, err := ingestQueries.BeginDryRun(ctx)
+		if err != nil {
+			log.Fatalf("Failed to begin dry run: %v", err)
+		}
+		defer dry.Rollback(ctx)
+		in.ingest = dry
+	}
+
+	start := time.Now()
+	for _, path := range flag.Args() {
+		if err := in.ingestFile(ctx, path); err != nil {
+			in.printReport(time.Since(start))
+			log.Fatalf("Ingestion of %s failed: %v", path, err)
+		}
+	}
+	in.printReport(time.Since(start))
+}
+
+// ingestFile streams one file through validation and batched upserts
+func (in *ingester) ingestFile(ctx context.Context, path string) error {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	records, err := newRecordReader(r)
+	if err != nil {
+		return err
+	}
+
+	batch := make([]staged, 0, in.batchSize)
+	for {
+		record, index, recordErr, err := records.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if recordErr != nil {
+			in.reject(path, index, recordErr)
+			continue
+		}
+
+		input, err := record.Input().Normalize(false)
+		if err != nil {
+			in.reject(path, index, err)
+			continue
+		}
+
+		batch = append(batch, staged{index: index, input: input})
+		if len(batch) == in.batchSize {
+			if err := in.flush(ctx, path, batch); err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+	}
+
+	return in.flush(ctx, path, batch)
+}
+
+// flush upserts a batch, retrying with exponential backoff. Errors reported
+// by Postgres itself are not retried since they would fail again: a batch
+// rejected for its data is split in half until the offending records are
+// found and rejected, and any other database error rejects the batch.
+func (in *ingester) flush(ctx context.Context, path string, batch []staged) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	inputs := make([]queries.QuoteInput, len(batch))
+	for i, record := range batch {
+		inputs[i] = record.input
+	}
+
+	backoff := time.Second
+	for attempt := 0; ; attempt++ {
+		result, err := in.ingest.UpsertBatch(ctx, inputs)
+		if err == nil {
+			in.report.Add(result)
+			log.Printf("Batch of %d: %d inserted, %d updated, %d unchanged",
+				len(batch), result.Inserted, result.Updated, result.Unchanged)
+			return nil
+		}
+
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			return in.bisect(ctx, path, batch, pgErr)
+		}
+		if attempt >= in.retries {
+			return err
+		}
+
+		log.Printf("Batch failed (attempt %d/%d): %v; retrying in %s", attempt+1, in.retries+1, err, backoff)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// bisect handles a batch Postgres refused. Data exceptions (class 22) and
+// constraint violations (class 23) come from individual records, so the
+// halves are flushed separately; a single record is rejected. Other errors
+// would fail for any record, so the whole batch is rejected.
+func (in *ingester) bisect(ctx context.Context, path string, batch []staged, pgErr *pgconn.PgError) error {
+	dataError := strings.HasPrefix(pgErr.Code, "22") || strings.HasPrefix(pgErr.Code, "23")
+	if !dataError || len(batch) == 1 {
+		log.Printf("Batch of %d rejected: %v", len(batch), pgErr)
+		for _, record := range batch {
+			in.reject(path, record.index, pgErr)
+		}
+		return nil
+	}
+
+	half := len(batch) / 2
+	if err := in.flush(ctx, path, batch[:half]); err != nil {
+		return err
+	}
+	return in.flush(ctx, path, batch[half:])
+}
+
+func (in *ingester) reject(file string, index int, err error) {
+	in.report.rejected = append(in.report.rejected, rejection{file: file, index: index, reason: err.Error()})
+}
+
+func (in *ingester) printReport(elapsed time.Duration) {
+	mode := ""
+	if in.dryRun {
+		mode = " (dry run, nothing written)"
+	}
+
+	fmt.Printf("\nIngestion report%s\n", mode)
+	fmt.Printf("  inserted:   %d\n", in.report.Inserted)
+	fmt.Printf("  updated:    %d\n", in.report.Updated)
+	fmt.Printf("  unchanged:  %d\n", in.report.Unchanged)
+	fmt.Printf("  duplicates: %d\n", in.report.Duplicates)
+	fmt.Printf("  rejected:   %d\n", len(in.report.rejected))
+	fmt.Printf("  elapsed:    %s\n", elapsed.Round(time.Millisecond))
+
+	for _, r := range in.report.rejected {
+		fmt.Printf("    %s record %d: %s\n", r.file, r.index, r.reason)
+	}
+}
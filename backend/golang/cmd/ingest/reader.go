@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"quotes-api/queries"
+)
+
+// recordReader streams IngestRecords from a JSON array, a single JSON object
+// or newline-delimited JSON objects without loading the whole file
+type recordReader struct {
+	dec     *json.Decoder
+	inArray bool
+	index   int
+}
+
+func newRecordReader(r io.Reader) (*recordReader, error) {
+	br := bufio.NewReader(r)
+
+	// Skip leading whitespace to see whether the input is an array
+	for {
+		b, err := br.Peek(1)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if b[0] != ' ' && b[0] != '\t' && b[0] != '\n' && b[0] != '\r' {
+			break
+		}
+		br.ReadByte()
+	}
+
+	rr := &recordReader{dec: json.NewDecoder(br)}
+	if b, err := br.Peek(1); err == nil && b[0] == '[' {
+		// Consume the opening bracket so elements decode one at a time
+		if _, err := rr.dec.Token(); err != nil {
+			return nil, err
+		}
+		rr.inArray = true
+	}
+	return rr, nil
+}
+
+// Next returns the next record and its 1-based position in the input. A
+// record that is valid JSON but has the wrong shape is returned with a
+// non-nil recordErr so the caller can reject it and carry on; any other error
+// ends the stream. Next returns io.EOF when the input is exhausted.
+func (rr *recordReader) Next() (record queries.IngestRecord, index int, recordErr error, err error) {
+	if !rr.dec.More() {
+		if rr.inArray {
+			// Consume the closing bracket
+			if _, err := rr.dec.Token(); err != nil {
+				return record, 0, nil, err
+			}
+		}
+		return record, 0, nil, io.EOF
+	}
+
+	var raw json.RawMessage
+	if err := rr.dec.Decode(&raw); err != nil {
+		return record, 0, nil, fmt.Errorf("record %d: %w", rr.index+1, err)
+	}
+	rr.index++
+
+	if err := json.Unmarshal(raw, &record); err != nil {
+		return record, rr.index, err, nil
+	}
+	return record, rr.index, nil, nil
+}
@@ -0,0 +1,201 @@
+// Command releval scores search relevance against a judgment file and diffs
+// runs, so ranking changes can be gated on metrics.
+//
+// Usage:
+//
+//	go run ./cmd/releval run [-k 10] [-sort relevance] [-mode hybrid] [-boost-author 3] [-label before] [-out run.json] JUDGMENTS
+//	go run ./cmd/releval diff [-metric ndcg] [-tolerance 0.01] BEFORE AFTER
+//
+// run searches every judged query through SearchQueries, prints nDCG@k, MRR,
+// precision@k and recall@k per query and on average, and saves the run with
+// -out. diff compares two saved runs and exits with status 1 when the mean
+// metric fell by more than the tolerance.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/joho/godotenv"
+	"quotes-api/queries"
+	"quotes-api/releval"
+)
+
+func main() {
+	log.SetFlags(0)
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	switch os.Args[1] {
+	case "run":
+		runCommand(os.Args[2:])
+	case "diff":
+		diffCommand(os.Args[2:])
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage:\n  %[1]s run [flags] JUDGMENTS\n  %[1]s diff [flags] BEFORE AFTER\n", os.Args[0])
+	os.Exit(2)
+}
+
+func runCommand(args []string) {
+	flags := flag.NewFlagSet("run", flag.ExitOnError)
+	k := flags.Int("k", 0, "cutoff for every metric (default: the judgment file's, or 10)")
+	sort := flags.String("sort", "relevance", "search sort: relevance, popularity, blended or created_at")
+	mode := flags.String("mode", "keyword", "search mode: keyword, semantic or hybrid")
+	label := flags.String("label", "", "name of the run in diffs")
+	out := flags.String("out", "", "save the run as JSON for later diffs")
+	defaults := queries.DefaultSearchBoosts()
+	boosts := defaults
+	flags.Float64Var(&boosts.Quote, "boost-quote", defaults.Quote, "quote field boost")
+	flags.Float64Var(&boosts.Author, "boost-author", defaults.Author, "author field boost")
+	flags.Float64Var(&boosts.Tags, "boost-tags", defaults.Tags, "tags field boost")
+	flags.Float64Var(&boosts.Category, "boost-category", defaults.Category, "category field boost")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	if !boosts.Valid() {
+		log.Fatalf("Invalid boosts: each must be finite and non-negative, and at least one positive")
+	}
+
+	judgments, err := releval.LoadJudgments(flags.Arg(0))
+	if err != nil {
+		log.Fatalf("Failed to load judgments: %v", err)
+	}
+
+	// Load environment variables from .env file
+	if err := godotenv.Load(); err != nil {
+		log.Printf("Warning: Could not load .env file: %v", err)
+	}
+
+	databaseURL := os.Getenv("DATABASE_URL")
+	if databaseURL == "" {
+		log.Fatal("DATABASE_URL environment variable is required")
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, databaseURL)
+	if err != nil {
+		log.Fatalf("Failed to create connection pool: %v", err)
+	}
+	defer pool.Close()
+
+	// Semantic modes embed queries like the server, configured by the same variables
+	embedder, err := queries.NewEmbedder(queries.EmbedderConfig{
+		Provider: os.Getenv("EMBEDDING_PROVIDER"),
+		URL:      os.Getenv("EMBEDDING_URL"),
+		APIKey:   os.Getenv("EMBEDDING_API_KEY"),
+		Model:    os.Getenv("EMBEDDING_MODEL"),
+	})
+	if err != nil {
+		log.Fatalf("Invalid embedding configuration: %v", err)
+	}
+
+	search := queries.NewSearchQueries(pool, queries.DefaultSearchConfig(), nil, embedder)
+	run, err := releval.Evaluate(ctx, search, judgments, releval.Options{
+		K:      *k,
+		Sort:   *sort,
+		Mode:   *mode,
+		Boosts: &boosts,
+		Label:  *label,
+	})
+	if err != nil {
+		log.Fatalf("Evaluation failed: %v", err)
+	}
+
+	printRun(run)
+
+	if *out != "" {
+		if err := run.Save(*out); err != nil {
+			log.Fatalf("Failed to save run: %v", err)
+		}
+		fmt.Printf("\nSaved to %s\n", *out)
+	}
+}
+
+func diffCommand(args []string) {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	metric := flags.String("metric", "ndcg", "metric to gate on: "+strings.Join(releval.MetricNames, ", "))
+	tolerance := flags.Float64("tolerance", 0.01, "largest allowed drop of the mean metric")
+	flags.Parse(args)
+
+	if flags.NArg() != 2 || !releval.ValidMetric(*metric) {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	before, err := releval.LoadRun(flags.Arg(0))
+	if err != nil {
+		log.Fatalf("Failed to load run: %v", err)
+	}
+	after, err := releval.LoadRun(flags.Arg(1))
+	if err != nil {
+		log.Fatalf("Failed to load run: %v", err)
+	}
+
+	diff := releval.Compare(before, after)
+	printDiff(diff, runName(before, flags.Arg(0)), runName(after, flags.Arg(1)), *metric, *tolerance)
+
+	if diff.Delta.Get(*metric) < -*tolerance {
+		fmt.Printf("\nFAIL: mean %s fell by %.4f, more than %.4f\n", *metric, -diff.Delta.Get(*metric), *tolerance)
+		os.Exit(1)
+	}
+	fmt.Printf("\nPASS: mean %s changed by %+.4f\n", *metric, diff.Delta.Get(*metric))
+}
+
+func runName(run releval.Run, path string) string {
+	if run.Options.Label != "" {
+		return run.Options.Label
+	}
+	return path
+}
+
+func printRun(run releval.Run) {
+	fmt.Printf("%-32s %8s %8s %8s %8s\n", "query", "ndcg", "mrr", "prec", "recall")
+	for _, q := range run.Queries {
+		printMetrics(q.ID, q.Metrics, "%8.4f")
+	}
+	fmt.Println()
+	printMetrics(fmt.Sprintf("mean@%d (%d queries)", run.Options.K, len(run.Queries)), run.Mean, "%8.4f")
+}
+
+func printDiff(diff releval.Diff, before, after, metric string, tolerance float64) {
+	fmt.Printf("%s -> %s\n\n", before, after)
+	fmt.Printf("%-32s %8s %8s %8s %8s\n", "", "ndcg", "mrr", "prec", "recall")
+	printMetrics("before", diff.Before, "%8.4f")
+	printMetrics("after", diff.After, "%8.4f")
+	printMetrics("delta", diff.Delta, "%+8.4f")
+
+	if regressions := diff.Regressions(metric, tolerance); len(regressions) > 0 {
+		fmt.Printf("\nQueries whose %s fell by more than %.4f:\n", metric, tolerance)
+		for _, q := range regressions {
+			fmt.Printf("  %-30s %.4f -> %.4f  (%q)\n", q.ID, q.Before.Get(metric), q.After.Get(metric), q.Query)
+		}
+	}
+	if len(diff.OnlyBefore) > 0 {
+		fmt.Printf("\nOnly in %s: %s\n", before, strings.Join(diff.OnlyBefore, ", "))
+	}
+	if len(diff.OnlyAfter) > 0 {
+		fmt.Printf("\nOnly in %s: %s\n", after, strings.Join(diff.OnlyAfter, ", "))
+	}
+}
+
+func printMetrics(name string, m releval.Metrics, format string) {
+	fmt.Printf("%-32s", name)
+	for _, metric := range releval.MetricNames {
+		fmt.Printf(" "+format, m.Get(metric))
+	}
+	fmt.Println()
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"quotes-api/queries"
+)
+
+// Config holds server settings loaded from the environment
+type Config struct {
+	DatabaseURL string
+	Port        string
+	// PublicBaseURL is the frontend origin used to build quote share links
+	PublicBaseURL string
+	// AdminAPIKey authorizes curator endpoints; they are disabled when empty
+	AdminAPIKey string
+	Search      queries.SearchConfig
+	// IndexRefreshInterval controls how often in-memory search indexes reload
+	IndexRefreshInterval time.Duration
+	// QueryTimeout bounds the database work of a search or browse request
+	QueryTimeout time.Duration
+	// ExportTimeout bounds a streamed export, which reads every matching quote
+	ExportTimeout time.Duration
+	// Embedding selects the embedder behind semantic and hybrid search
+	Embedding queries.EmbedderConfig
+	// DailyQuoteRepeatDays is how many days apart the quote of the day may repeat
+	DailyQuoteRepeatDays int
+	// DailyQuoteBackfillDays is how many days back a missing quote of the day is still picked
+	DailyQuoteBackfillDays int
+}
+
+// LoadConfig reads server settings from environment variables, applying defaults
+func LoadConfig() (Config, error) {
+	config := Config{
+		DatabaseURL:            os.Getenv("DATABASE_URL"),
+		Port:                   os.Getenv("PORT"),
+		PublicBaseURL:          os.Getenv("PUBLIC_BASE_URL"),
+		AdminAPIKey:            os.Getenv("ADMIN_API_KEY"),
+		Search:                 queries.DefaultSearchConfig(),
+		IndexRefreshInterval:   15 * time.Minute,
+		QueryTimeout:           10 * time.Second,
+		ExportTimeout:          5 * time.Minute,
+		DailyQuoteRepeatDays:   365,
+		DailyQuoteBackfillDays: 30,
+		Embedding: queries.EmbedderConfig{
+			Provider: os.Getenv("EMBEDDING_PROVIDER"),
+			URL:      os.Getenv("EMBEDDING_URL"),
+			APIKey:   os.Getenv("EMBEDDING_API_KEY"),
+			Model:    os.Getenv("EMBEDDING_MODEL"),
+		},
+	}
+
+	if config.DatabaseURL == "" {
+		return Config{}, fmt.Errorf("DATABASE_URL environment variable is required")
+	}
+	if config.Port == "" {
+		config.Port = "8080"
+	}
+	if config.PublicBaseURL == "" {
+		config.PublicBaseURL = "http://localhost:3000"
+	}
+
+	// Field boosts for multi-field search
+	boosts := &config.Search.Boosts
+	for name, target := range map[string]*float64{
+		"SEARCH_BOOST_QUOTE":    &boosts.Quote,
+		"SEARCH_BOOST_AUTHOR":   &boosts.Author,
+		"SEARCH_BOOST_TAGS":     &boosts.Tags,
+		"SEARCH_BOOST_CATEGORY": &boosts.Category,
+	} {
+		if err := envFloat(name, target); err != nil {
+			return Config{}, err
+		}
+	}
+	if !boosts.Valid() {
+		return Config{}, fmt.Errorf("SEARCH_BOOST_* must be finite and non-negative, and at least one positive")
+	}
+
+	// Weights of the blended search ranking
+	ranking := &config.Search.Ranking
+	for name, target := range map[string]*float64{
+		"SEARCH_RANK_SCORE_WEIGHT":      &ranking.Score,
+		"SEARCH_RANK_POPULARITY_WEIGHT": &ranking.Popularity,
+		"SEARCH_RANK_RECENCY_WEIGHT":    &ranking.Recency,
+	} {
+		if err := envFloat(name, target); err != nil {
+			return Config{}, err
+		}
+	}
+	if err := envDuration("SEARCH_RANK_RECENCY_HALF_LIFE", &ranking.RecencyHalfLife); err != nil {
+		return Config{}, err
+	}
+
+	if err := envInt("SEARCH_SUGGESTION_THRESHOLD", &config.Search.SuggestionThreshold); err != nil {
+		return Config{}, err
+	}
+	if err := envDuration("INDEX_REFRESH_INTERVAL", &config.IndexRefreshInterval); err != nil {
+		return Config{}, err
+	}
+	if err := envDuration("QUERY_TIMEOUT", &config.QueryTimeout); err != nil {
+		return Config{}, err
+	}
+	if err := envDuration("EXPORT_TIMEOUT", &config.ExportTimeout); err != nil {
+		return Config{}, err
+	}
+	if err := envDuration("SEARCH_FACET_TIMEOUT", &config.Search.FacetTimeout); err != nil {
+		return Config{}, err
+	}
+	if err := envBool("SEARCH_SINGLE_QUERY", &config.Search.SingleQuery); err != nil {
+		return Config{}, err
+	}
+	if err := envInt("SEARCH_SEMANTIC_CANDIDATES", &config.Search.SemanticCandidates); err != nil {
+		return Config{}, err
+	}
+	if err := envInt("SEARCH_RRF_K", &config.Search.RRFK); err != nil {
+		return Config{}, err
+	}
+	if err := envInt("QUOTE_OF_THE_DAY_REPEAT_DAYS", &config.DailyQuoteRepeatDays); err != nil {
+		return Config{}, err
+	}
+	if err := envInt("QUOTE_OF_THE_DAY_BACKFILL_DAYS", &config.DailyQuoteBackfillDays); err != nil {
+		return Config{}, err
+	}
+
+	return config, nil
+}
+
+// envFloat overrides target with the named variable when it is set
+func envFloat(name string, target *float64) error {
+	value := os.Getenv(name)
+	if value == "" {
+		return nil
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil || parsed < 0 {
+		return fmt.Errorf("%s must be a non-negative number, got %q", name, value)
+	}
+	*target = parsed
+	return nil
+}
+
+// envInt overrides target with the named variable when it is set
+func envInt(name string, target *int) error {
+	value := os.Getenv(name)
+	if value == "" {
+		return nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < 0 {
+		return fmt.Errorf("%s must be a non-negative integer, got %q", name, value)
+	}
+	*target = parsed
+	return nil
+}
+
+// envBool overrides target with the named variable when it is set
+func envBool(name string, target *bool) error {
+	value := os.Getenv(name)
+	if value == "" {
+		return nil
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("%s must be true or false, got %q", name, value)
+	}
+	*target = parsed
+	return nil
+}
+
+// envDuration overrides target with the named variable when it is set
+func envDuration(name string, target *time.Duration) error {
+	value := os.Getenv(name)
+	if value == "" {
+		return nil
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed <= 0 {
+		return fmt.Errorf("%s must be a positive duration such as 10m, got %q", name, value)
+	}
+	*target = parsed
+	return nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"quotes-api/queries"
+)
+
+// exportEncoder writes quotes in one export format. Begin and End wrap the
+// stream; Encode is called once per quote and Flush drains any buffering
+// before the response is flushed.
+type exportEncoder interface {
+	ContentType() string
+	Extension() string
+	Begin() error
+	Encode(q queries.Quote) error
+	Flush() error
+	End() error
+}
+
+func newExportEncoder(format string, w io.Writer) (exportEncoder, error) {
+	switch format {
+	case "", "ndjson":
+		return &ndjsonEncoder{enc: json.NewEncoder(w)}, nil
+	case "json":
+		return &jsonArrayEncoder{w: w}, nil
+	case "csv":
+		return &csvEncoder{w: csv.NewWriter(w)}, nil
+	}
+	return nil, fmt.Errorf("unknown export format %q", format)
+}
+
+// ndjsonEncoder writes one ingestion record per line
+type ndjsonEncoder struct {
+	enc *json.Encoder
+}
+
+func (e *ndjsonEncoder) ContentType() string { return "application/x-ndjson" }
+func (e *ndjsonEncoder) Extension() string   { return "ndjson" }
+func (e *ndjsonEncoder) Begin() error        { return nil }
+func (e *ndjsonEncoder) Flush() error        { return nil }
+func (e *ndjsonEncoder) End() error          { return nil }
+
+func (e *ndjsonEncoder) Encode(q queries.Quote) error {
+	return e.enc.Encode(q.Record())
+}
+
+// jsonArrayEncoder writes an array of ingestion records, like aigen/first.json
+type jsonArrayEncoder struct {
+	w     io.Writer
+	count int
+}
+
+func (e *jsonArrayEncoder) ContentType() string { return "application/json" }
+func (e *jsonArrayEncoder) Extension() string   { return "json" }
+func (e *jsonArrayEncoder) Flush() error        { return nil }
+
+func (e *jsonArrayEncoder) Begin() error {
+	_, err := io.WriteString(e.w, "[")
+	return err
+}
+
+func (e *jsonArrayEncoder) Encode(q queries.Quote) error {
+	record, err := json.Marshal(q.Record())
+	if err != nil {
+		return err
+	}
+	separator := ",\n"
+	if e.count == 0 {
+		separator = "\n"
+	}
+	e.count++
+	if _, err := io.WriteString(e.w, separator); err != nil {
+		return err
+	}
+	_, err = e.w.Write(record)
+	return err
+}
+
+func (e *jsonArrayEncoder) End() error {
+	_, err := io.WriteString(e.w, "\n]\n")
+	return err
+}
+
+// csvEncoder writes a spreadsheet-friendly table. Tags are written as a JSON
+// array so a tag containing any separator still reads back as one tag.
+type csvEncoder struct {
+	w *csv.Writer
+}
+
+func (e *csvEncoder) ContentType() string { return "text/csv; charset=utf-8" }
+func (e *csvEncoder) Extension() string   { return "csv" }
+
+func (e *csvEncoder) Begin() error {
+	return e.w.Write([]string{"id", "quote", "author", "category", "tags", "popularity", "created_at"})
+}
+
+func (e *csvEncoder) Encode(q queries.Quote) error {
+	tags := q.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	tagsJSON, err := json.Marshal(tags)
+	if err != nil {
+		return err
+	}
+
+	var category, popularity, createdAt string
+	if q.Category != nil {
+		category = *q.Category
+	}
+	if q.Popularity != nil {
+		popularity = strconv.FormatFloat(*q.Popularity, 'f', -1, 64)
+	}
+	if q.CreatedAt != nil {
+		createdAt = *q.CreatedAt
+	}
+	return e.w.Write([]string{
+		strconv.Itoa(q.ID), q.Quote, q.Author, category,
+		string(tagsJSON), popularity, createdAt,
+	})
+}
+
+func (e *csvEncoder) End() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e *csvEncoder) Flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"quotes-api/queries"
+)
+
+// encodeAll runs quotes through the encoder for format and returns the output
+func encodeAll(t *testing.T, format string, quotes ...queries.Quote) string {
+	t.Helper()
+
+	var b strings.Builder
+	enc, err := newExportEncoder(format, &b)
+	if err != nil {
+		t.Fatalf("newExportEncoder(%q): %v", format, err)
+	}
+	if err := enc.Begin(); err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	for _, q := range quotes {
+		if err := enc.Encode(q); err != nil {
+			t.Fatalf("Encode: %v", err)
+		}
+		if err := enc.Flush(); err != nil {
+			t.Fatalf("Flush: %v", err)
+		}
+	}
+	if err := enc.End(); err != nil {
+		t.Fatalf("End: %v", err)
+	}
+	return b.String()
+}
+
+func exportQuotes() []queries.Quote {
+	category := "life"
+	popularity := 0.5
+	createdAt := "2026-10-01T00:00:00Z"
+	return []queries.Quote{
+		{ID: 1, Quote: `He said "carry on", then left`, Author: "Doe, Jane", Category: &category,
+			Tags: []string{"courage", "self help", "a;b"}, Popularity: &popularity, CreatedAt: &createdAt},
+		{ID: 2, Quote: "Two\nlines", Author: "Anon"},
+	}
+}
+
+func TestCSVEncoder(t *testing.T) {
+	got := encodeAll(t, "csv", exportQuotes()...)
+	want := "id,quote,author,category,tags,popularity,created_at\n" +
+		`1,"He said ""carry on"", then left","Doe, Jane",life,"[""courage"",""self help"",""a;b""]",0.5,2026-10-01T00:00:00Z` + "\n" +
+		"2,\"Two\nlines\",Anon,,[],,\n"
+	if got != want {
+		t.Errorf("csv =\n%s\nwant\n%s", got, want)
+	}
+
+	// Every tags cell reads back as the quote's tags, separators included
+	rows, err := csv.NewReader(strings.NewReader(got)).ReadAll()
+	if err != nil {
+		t.Fatalf("csv export does not parse: %v", err)
+	}
+	for i, q := range exportQuotes() {
+		var tags []string
+		if err := json.Unmarshal([]byte(rows[i+1][4]), &tags); err != nil {
+			t.Fatalf("row %d tags %q: %v", i+1, rows[i+1][4], err)
+		}
+		if len(tags) != len(q.Tags) || (len(tags) > 0 && !reflect.DeepEqual(tags, q.Tags)) {
+			t.Errorf("row %d tags = %q, want %q", i+1, tags, q.Tags)
+		}
+	}
+}
+
+func TestNDJSONEncoder(t *testing.T) {
+	got := encodeAll(t, "ndjson", exportQuotes()...)
+	lines := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+	if len(lines) != 2 || !strings.HasSuffix(got, "\n") {
+		t.Fatalf("ndjson is not one record per line:\n%s", got)
+	}
+
+	var record queries.IngestRecord
+	if err := json.Unmarshal([]byte(lines[1]), &record); err != nil {
+		t.Fatalf("line 2: %v", err)
+	}
+	if record.Quote != "Two\nlines" || record.Tags == nil || len(record.Tags) != 0 {
+		t.Errorf("line 2 = %+v", record)
+	}
+
+	if got := encodeAll(t, ""); got != "" {
+		t.Errorf("empty ndjson export = %q", got)
+	}
+}
+
+func TestJSONArrayEncoder(t *testing.T) {
+	got := encodeAll(t, "json", exportQuotes()...)
+	if !strings.HasPrefix(got, "[\n{") || !strings.HasSuffix(got, "}\n]\n") || strings.Count(got, "},\n{") != 1 {
+		t.Errorf("json array framing:\n%s", got)
+	}
+
+	var records []queries.IngestRecord
+	if err := json.Unmarshal([]byte(got), &records); err != nil {
+		t.Fatalf("json export does not parse: %v", err)
+	}
+	if len(records) != 2 || records[0].Author != "Doe, Jane" || len(records[0].Tags) != 3 {
+		t.Errorf("records = %+v", records)
+	}
+
+	// An export that matched nothing is still a valid, empty array
+	if err := json.Unmarshal([]byte(encodeAll(t, "json")), &records); err != nil || len(records) != 0 {
+		t.Errorf("empty json export = %+v, %v", records, err)
+	}
+}
+
+func TestNewExportEncoderUnknownFormat(t *testing.T) {
+	if _, err := newExportEncoder("xml", &strings.Builder{}); err == nil {
+		t.Errorf("newExportEncoder accepted xml")
+	}
+}
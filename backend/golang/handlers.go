@@ -1,11 +1,16 @@
 package main
 
 import (
+	"context"
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"quotes-api/queries"
@@ -13,15 +18,43 @@ import (
 
 type Handlers struct {
 	db            *pgxpool.Pool
+	config        Config
 	searchQueries *queries.SearchQueries
 	browseQueries *queries.BrowseQueries
+	quoteQueries  *queries.QuoteQueries
+	collections   *queries.CollectionQueries
+	themes        *queries.ThemeQueries
+	dailyQuotes   *queries.DailyQuoteQueries
+	users         *queries.UserQueries
+	favorites     *queries.FavoriteQueries
+	authorQueries *queries.AuthorQueries
+	catalogue     *queries.CatalogueQueries
+	exportQueries *queries.ExportQueries
+	spelling      *queries.SpellingIndex
+	autocomplete  *queries.AutocompleteIndex
 }
 
-func NewHandlers(db *pgxpool.Pool) *Handlers {
+func NewHandlers(db *pgxpool.Pool, config Config, spelling *queries.SpellingIndex, autocomplete *queries.AutocompleteIndex, embedder queries.Embedder) *Handlers {
+	browseQueries := queries.NewBrowseQueries(db, config.Search)
+	searchQueries := queries.NewSearchQueries(db, config.Search, spelling, embedder)
+	quoteQueries := queries.NewQuoteQueries(db, config.PublicBaseURL)
+	collections := queries.NewCollectionQueries(db, quoteQueries, browseQueries)
 	return &Handlers{
 		db:            db,
-		searchQueries: queries.NewSearchQueries(db),
-		browseQueries: queries.NewBrowseQueries(db),
+		config:        config,
+		searchQueries: searchQueries,
+		browseQueries: browseQueries,
+		quoteQueries:  quoteQueries,
+		collections:   collections,
+		themes:        queries.NewThemeQueries(db, searchQueries, browseQueries, collections),
+		dailyQuotes:   queries.NewDailyQuoteQueries(db, quoteQueries, browseQueries, config.DailyQuoteRepeatDays, config.DailyQuoteBackfillDays),
+		users:         queries.NewUserQueries(db),
+		favorites:     queries.NewFavoriteQueries(db),
+		authorQueries: queries.NewAuthorQueries(db, browseQueries),
+		catalogue:     queries.NewCatalogueQueries(db, browseQueries),
+		exportQueries: queries.NewExportQueries(db, browseQueries, searchQueries),
+		spelling:      spelling,
+		autocomplete:  autocomplete,
 	}
 }
 
@@ -41,97 +74,1267 @@ func (h *Handlers) HealthHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// QueryErrorResponse is returned with 400 when the search query cannot be parsed
+type QueryErrorResponse struct {
+	Error    string `json:"error"`
+	Message  string `json:"message"`
+	Position int    `json:"position"`
+	Token    string `json:"token"`
+}
+
+// queryContext bounds the database work for a request. It is cancelled when
+// the client disconnects or QueryTimeout passes.
+func (h *Handlers) queryContext(r *http.Request) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(r.Context(), h.config.QueryTimeout)
+}
+
+// writeDatabaseError reports a failed query, answering 504 when the request
+// ran out of time. Nothing is written once the client has gone away.
+func writeDatabaseError(w http.ResponseWriter, ctx context.Context, err error, logPrefix, body string) {
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		log.Printf("%s: timed out: %v", logPrefix, err)
+		http.Error(w, `{"error": "Query timed out"}`, http.StatusGatewayTimeout)
+	case errors.Is(ctx.Err(), context.Canceled):
+		log.Printf("%s: client disconnected", logPrefix)
+	default:
+		log.Printf("%s: %v", logPrefix, err)
+		http.Error(w, body, http.StatusInternalServerError)
+	}
+}
+
+func writeQueryError(w http.ResponseWriter, parseErr *queries.QueryParseError) {
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(QueryErrorResponse{
+		Error:    "Invalid query",
+		Message:  parseErr.Message,
+		Position: parseErr.Position,
+		Token:    parseErr.Token,
+	})
+}
+
 func (h *Handlers) SearchHandler(w http.ResponseWriter, r *http.Request) {
+	h.search(w, r, 0)
+}
+
+// search serves a search, or a browse when q is empty, restricted to the
+// favorites of favoritesOf when it is set
+func (h *Handlers) search(w http.ResponseWriter, r *http.Request, favoritesOf int) {
+	w.Header().Set("Content-Type", "application/json")
+	
+	// Get search query from URL parameter (can be empty for browse mode)
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	
+	// Parse all browse parameters (including filters)
+	params, err := h.parseBrowseParams(r)
+	if err != nil {
+		log.Printf("Invalid search parameters: %v", err)
+		http.Error(w, `{"error": "Invalid parameters"}`, http.StatusBadRequest)
+		return
+	}
+	params.FavoritesOf = favoritesOf
+
+	ctx, cancel := h.queryContext(r)
+	defer cancel()
+
+	if query == "" {
+		// Browse mode: no search query, use browse logic
+		response, err := h.browseQueries.Browse(ctx, params)
+		if errors.Is(err, queries.ErrInvalidCursor) {
+			http.Error(w, `{"error": "Invalid cursor"}`, http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			writeDatabaseError(w, ctx, err, "Browse query failed", `{"error": "Database query failed"}`)
+			return
+		}
+		h.markFavorites(ctx, r, response.Quotes)
+
+		json.NewEncoder(w).Encode(response)
+	} else {
+		// Parse field-scoped query syntax before touching the database
+		parsed, err := queries.ParseQuery(query)
+		if err != nil {
+			var parseErr *queries.QueryParseError
+			if errors.As(err, &parseErr) {
+				writeQueryError(w, parseErr)
+				return
+			}
+			log.Printf("Query parse failed: %v", err)
+			http.Error(w, `{"error": "Invalid query"}`, http.StatusBadRequest)
+			return
+		}
+
+		// Search mode: use search with filters, falling back to fuzzy matching
+		response, err := h.searchQueries.Search(ctx, parsed, params)
+		if errors.Is(err, queries.ErrInvalidCursor) {
+			http.Error(w, `{"error": "Invalid cursor"}`, http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, queries.ErrSemanticUnavailable) {
+			http.Error(w, `{"error": "Semantic search is not configured"}`, http.StatusServiceUnavailable)
+			return
+		}
+		if err != nil {
+			writeDatabaseError(w, ctx, err, "Search with filters failed", `{"error": "Database query failed"}`)
+			return
+		}
+		h.markFavorites(ctx, r, response.Quotes)
+
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// SearchExplainHandler reports the SQL, arguments, parsed query and query
+// plans of a search without returning its results
+func (h *Handlers) SearchExplainHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		http.Error(w, `{"error": "Query parameter q is required"}`, http.StatusBadRequest)
+		return
+	}
+
+	params, err := h.parseBrowseParams(r)
+	if err != nil {
+		log.Printf("Invalid explain parameters: %v", err)
+		http.Error(w, `{"error": "Invalid parameters"}`, http.StatusBadRequest)
+		return
+	}
+
+	parsed, err := queries.ParseQuery(query)
+	if err != nil {
+		var parseErr *queries.QueryParseError
+		if errors.As(err, &parseErr) {
+			writeQueryError(w, parseErr)
+			return
+		}
+		http.Error(w, `{"error": "Invalid query"}`, http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := h.queryContext(r)
+	defer cancel()
+
+	response, err := h.searchQueries.Explain(ctx, parsed, params)
+	if errors.Is(err, queries.ErrInvalidCursor) {
+		http.Error(w, `{"error": "Invalid cursor"}`, http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, queries.ErrSemanticUnavailable) {
+		http.Error(w, `{"error": "Semantic search is not configured"}`, http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil {
+		writeDatabaseError(w, ctx, err, "Search explain failed", `{"error": "Database query failed"}`)
+		return
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) BrowseHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	
+	// Parse query parameters into BrowseParams
+	params, err := h.parseBrowseParams(r)
+	if err != nil {
+		log.Printf("Invalid browse parameters: %v", err)
+		http.Error(w, `{"error": "Invalid parameters"}`, http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := h.queryContext(r)
+	defer cancel()
+
+	// Execute database queries and build the response
+	response, err := h.browseQueries.Browse(ctx, params)
+	if errors.Is(err, queries.ErrInvalidCursor) {
+		http.Error(w, `{"error": "Invalid cursor"}`, http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		writeDatabaseError(w, ctx, err, "Browse query failed", `{"error": "Database query failed"}`)
+		return
+	}
+	h.markFavorites(ctx, r, response.Quotes)
+
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) SpellingHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		http.Error(w, `{"error": "Query parameter q is required"}`, http.StatusBadRequest)
+		return
+	}
+
+	parsed, err := queries.ParseQuery(query)
+	if err != nil {
+		var parseErr *queries.QueryParseError
+		if errors.As(err, &parseErr) {
+			writeQueryError(w, parseErr)
+			return
+		}
+		http.Error(w, `{"error": "Invalid query"}`, http.StatusBadRequest)
+		return
+	}
+
+	suggestions := h.spelling.Suggest(parsed, 5)
+	if suggestions == nil {
+		suggestions = []string{}
+	}
+
+	json.NewEncoder(w).Encode(queries.SpellingResponse{
+		Query:       query,
+		Suggestions: suggestions,
+	})
+}
+
+func (h *Handlers) AutocompleteHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	query := r.URL.Query().Get("q")
+
+	// Parse limit
+	limit := 8
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 50 {
+			limit = l
+		}
+	}
+
+	json.NewEncoder(w).Encode(queries.AutocompleteResponse{
+		Query:       query,
+		Completions: h.autocomplete.Complete(query, limit),
+	})
+}
+
+func (h *Handlers) QuoteHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		http.Error(w, `{"error": "Invalid quote id"}`, http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := h.queryContext(r)
+	defer cancel()
+
+	quote, err := h.quoteQueries.GetByID(ctx, id)
+	if errors.Is(err, queries.ErrQuoteNotFound) {
+		http.Error(w, `{"error": "Quote not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		writeDatabaseError(w, ctx, err, "Quote lookup failed", `{"error": "Database query failed"}`)
+		return
+	}
+
+	json.NewEncoder(w).Encode(quote)
+}
+
+func (h *Handlers) QuoteBatchHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// Parse comma-separated ids
+	idsStr := r.URL.Query().Get("ids")
+	if idsStr == "" {
+		http.Error(w, `{"error": "Query parameter ids is required"}`, http.StatusBadRequest)
+		return
+	}
+
+	var ids []int
+	for _, part := range strings.Split(idsStr, ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || id <= 0 {
+			http.Error(w, `{"error": "Invalid quote id"}`, http.StatusBadRequest)
+			return
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) > queries.MaxBatchIDs {
+		http.Error(w, fmt.Sprintf(`{"error": "At most %d ids per request"}`, queries.MaxBatchIDs), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := h.queryContext(r)
+	defer cancel()
+
+	response, err := h.quoteQueries.GetByIDs(ctx, ids)
+	if err != nil {
+		writeDatabaseError(w, ctx, err, "Quote batch lookup failed", `{"error": "Database query failed"}`)
+		return
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// ValidationErrorResponse is returned with 400 when a quote write is invalid
+type ValidationErrorResponse struct {
+	Error   string `json:"error"`
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// maxWriteBodyBytes caps the size of JSON request bodies
+const maxWriteBodyBytes = 1 << 20
+
+// RequireAdmin allows the request only when it carries the configured admin
+// key as a bearer token or X-API-Key header
+func (h *Handlers) RequireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if h.config.AdminAPIKey == "" {
+			http.Error(w, `{"error": "Admin API is disabled"}`, http.StatusForbidden)
+			return
+		}
+
+		if subtle.ConstantTimeCompare([]byte(requestAPIKey(r)), []byte(h.config.AdminAPIKey)) != 1 {
+			http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+			return
+		}
+
+		next(w, r.WithContext(context.WithValue(r.Context(), adminContextKey{}, true)))
+	}
+}
+
+// adminContextKey marks a request RequireAdmin let through
+type adminContextKey struct{}
+
+// requestAPIKey returns the key sent as a bearer token or X-API-Key header
+func requestAPIKey(r *http.Request) string {
+	if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return bearer
+	}
+	return r.Header.Get("X-API-Key")
+}
+
+// userContextKey stores the user RequireUser authenticated in the request context
+type userContextKey struct{}
+
+// RequireUser allows the request only when it carries a user's API key, and
+// makes the user available to the handler through contextUser
+func (h *Handlers) RequireUser(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		user, err := h.users.Authenticate(r.Context(), requestAPIKey(r))
+		if errors.Is(err, queries.ErrUnknownAPIKey) {
+			http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			log.Printf("User authentication failed: %v", err)
+			http.Error(w, `{"error": "Database query failed"}`, http.StatusInternalServerError)
+			return
+		}
+
+		next(w, r.WithContext(context.WithValue(r.Context(), userContextKey{}, user)))
+	}
+}
+
+// contextUser returns the user RequireUser authenticated, if any
+func contextUser(r *http.Request) (queries.User, bool) {
+	user, ok := r.Context().Value(userContextKey{}).(queries.User)
+	return user, ok
+}
+
+// identify returns the user behind the request on endpoints that do not
+// require one. A missing or unknown key is an anonymous request, not an error.
+func (h *Handlers) identify(ctx context.Context, r *http.Request) (queries.User, bool) {
+	if user, ok := contextUser(r); ok {
+		return user, true
+	}
+	key := requestAPIKey(r)
+	if key == "" {
+		return queries.User{}, false
+	}
+	user, err := h.users.Authenticate(ctx, key)
+	if err != nil {
+		if !errors.Is(err, queries.ErrUnknownAPIKey) {
+			log.Printf("User authentication failed: %v", err)
+		}
+		return queries.User{}, false
+	}
+	return user, true
+}
+
+// markFavorites sets is_favorite on quotes when the request identifies a
+// user. A failed lookup leaves the flag unset rather than failing the request.
+func (h *Handlers) markFavorites(ctx context.Context, r *http.Request, quotes []queries.Quote) {
+	user, ok := h.identify(ctx, r)
+	if !ok {
+		return
+	}
+	if err := h.favorites.Mark(ctx, user.ID, quotes); err != nil {
+		log.Printf("Favorite lookup failed: %v", err)
+	}
+}
+
+// CreateUserRequest is the body of the user create endpoint
+type CreateUserRequest struct {
+	Name string `json:"name"`
+}
+
+// CreateUserHandler creates a user and returns its API key, which cannot be
+// retrieved again
+func (h *Handlers) CreateUserHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var request CreateUserRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxWriteBodyBytes)).Decode(&request); err != nil {
+		http.Error(w, `{"error": "Invalid JSON body"}`, http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.users.CreateUser(r.Context(), request.Name)
+	var validationErr *queries.ValidationError
+	if errors.As(err, &validationErr) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ValidationErrorResponse{
+			Error:   "Invalid user",
+			Field:   validationErr.Field,
+			Message: validationErr.Message,
+		})
+		return
+	}
+	if err != nil {
+		log.Printf("User create failed: %v", err)
+		http.Error(w, `{"error": "Database query failed"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(user)
+}
+
+// MeHandler returns the user the API key belongs to
+func (h *Handlers) MeHandler(w http.ResponseWriter, r *http.Request) {
+	user, _ := contextUser(r)
+	json.NewEncoder(w).Encode(user)
+}
+
+// FavoritesHandler lists the user's favorites with the filters, sorts,
+// paging and facets of search, or of browse when q is empty
+func (h *Handlers) FavoritesHandler(w http.ResponseWriter, r *http.Request) {
+	user, _ := contextUser(r)
+	h.search(w, r, user.ID)
+}
+
+func (h *Handlers) AddFavoriteHandler(w http.ResponseWriter, r *http.Request) {
+	h.setFavorite(w, r, h.favorites.Add, "Favorite add failed")
+}
+
+func (h *Handlers) RemoveFavoriteHandler(w http.ResponseWriter, r *http.Request) {
+	h.setFavorite(w, r, h.favorites.Remove, "Favorite remove failed")
+}
+
+// setFavorite applies a favorite change for the quote in the path
+func (h *Handlers) setFavorite(w http.ResponseWriter, r *http.Request, apply func(context.Context, int, int) error, logPrefix string) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		http.Error(w, `{"error": "Invalid quote id"}`, http.StatusBadRequest)
+		return
+	}
+
+	user, _ := contextUser(r)
+	err = apply(r.Context(), user.ID, id)
+	if errors.Is(err, queries.ErrQuoteNotFound) {
+		http.Error(w, `{"error": "Quote not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("%s: %v", logPrefix, err)
+		http.Error(w, `{"error": "Database query failed"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handlers) CreateQuoteHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var input queries.QuoteInput
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxWriteBodyBytes)).Decode(&input); err != nil {
+		http.Error(w, `{"error": "Invalid JSON body"}`, http.StatusBadRequest)
+		return
+	}
+
+	quote, err := h.quoteQueries.CreateQuote(r.Context(), input)
+	if err != nil {
+		writeQuoteWriteError(w, err, "Quote create failed")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(quote)
+}
+
+func (h *Handlers) UpdateQuoteHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		http.Error(w, `{"error": "Invalid quote id"}`, http.StatusBadRequest)
+		return
+	}
+
+	var input queries.QuoteInput
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxWriteBodyBytes)).Decode(&input); err != nil {
+		http.Error(w, `{"error": "Invalid JSON body"}`, http.StatusBadRequest)
+		return
+	}
+
+	quote, err := h.quoteQueries.UpdateQuote(r.Context(), id, input)
+	if err != nil {
+		writeQuoteWriteError(w, err, "Quote update failed")
+		return
+	}
+
+	json.NewEncoder(w).Encode(quote)
+}
+
+func (h *Handlers) DeleteQuoteHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		http.Error(w, `{"error": "Invalid quote id"}`, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.quoteQueries.DeleteQuote(r.Context(), id); err != nil {
+		writeQuoteWriteError(w, err, "Quote delete failed")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeQuoteWriteError maps quote write errors to 400, 404, 409 or 500 responses
+func writeQuoteWriteError(w http.ResponseWriter, err error, logPrefix string) {
+	var validationErr *queries.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ValidationErrorResponse{
+			Error:   "Invalid quote",
+			Field:   validationErr.Field,
+			Message: validationErr.Message,
+		})
+	case errors.Is(err, queries.ErrQuoteNotFound):
+		http.Error(w, `{"error": "Quote not found"}`, http.StatusNotFound)
+	case errors.Is(err, queries.ErrDuplicateQuote):
+		http.Error(w, `{"error": "This quote by this author already exists"}`, http.StatusConflict)
+	default:
+		log.Printf("%s: %v", logPrefix, err)
+		http.Error(w, `{"error": "Database query failed"}`, http.StatusInternalServerError)
+	}
+}
+
+// collectionsFor returns the collection queries for a request: the user's
+// personal collections under RequireUser, editorial ones otherwise
+func (h *Handlers) collectionsFor(r *http.Request) *queries.CollectionQueries {
+	if user, ok := contextUser(r); ok {
+		return h.collections.ForOwner(user.ID)
+	}
+	if admin, _ := r.Context().Value(adminContextKey{}).(bool); admin {
+		return h.collections.WithDrafts()
+	}
+	return h.collections
+}
+
+func (h *Handlers) CollectionsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	params := queries.CollectionListParams{Page: 1, Limit: 20}
+	params.Page, params.Limit = parsePaging(r, params.Page, params.Limit)
+
+	ctx, cancel := h.queryContext(r)
+	defer cancel()
+
+	response, err := h.collectionsFor(r).ListCollections(ctx, params)
+	if err != nil {
+		writeDatabaseError(w, ctx, err, "Collection list query failed", `{"error": "Database query failed"}`)
+		return
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) CollectionHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	ctx, cancel := h.queryContext(r)
+	defer cancel()
+
+	collection, err := h.collectionsFor(r).GetCollection(ctx, r.PathValue("slug"))
+	if errors.Is(err, queries.ErrCollectionNotFound) {
+		http.Error(w, `{"error": "Collection not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		writeDatabaseError(w, ctx, err, "Collection query failed", `{"error": "Database query failed"}`)
+		return
+	}
+	h.markFavorites(ctx, r, collection.Quotes)
+
+	json.NewEncoder(w).Encode(collection)
+}
+
+func (h *Handlers) CreateCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var input queries.CollectionInput
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxWriteBodyBytes)).Decode(&input); err != nil {
+		http.Error(w, `{"error": "Invalid JSON body"}`, http.StatusBadRequest)
+		return
+	}
+
+	collection, err := h.collectionsFor(r).CreateCollection(r.Context(), input)
+	if err != nil {
+		writeCollectionWriteError(w, err, "Collection create failed")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(collection)
+}
+
+func (h *Handlers) UpdateCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var input queries.CollectionInput
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxWriteBodyBytes)).Decode(&input); err != nil {
+		http.Error(w, `{"error": "Invalid JSON body"}`, http.StatusBadRequest)
+		return
+	}
+
+	collection, err := h.collectionsFor(r).UpdateCollection(r.Context(), r.PathValue("slug"), input)
+	if err != nil {
+		writeCollectionWriteError(w, err, "Collection update failed")
+		return
+	}
+
+	json.NewEncoder(w).Encode(collection)
+}
+
+func (h *Handlers) DeleteCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	if err := h.collectionsFor(r).DeleteCollection(r.Context(), r.PathValue("slug")); err != nil {
+		writeCollectionWriteError(w, err, "Collection delete failed")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CollectionQuotesRequest is the body of the add and reorder endpoints
+type CollectionQuotesRequest struct {
+	QuoteIDs []int `json:"quote_ids"`
+}
+
+func (h *Handlers) AddCollectionQuotesHandler(w http.ResponseWriter, r *http.Request) {
+	h.collectionQuotesHandler(w, r, (*queries.CollectionQueries).AddQuotes, "Collection add failed")
+}
+
+func (h *Handlers) ReorderCollectionQuotesHandler(w http.ResponseWriter, r *http.Request) {
+	h.collectionQuotesHandler(w, r, (*queries.CollectionQueries).ReorderQuotes, "Collection reorder failed")
+}
+
+// collectionQuotesHandler applies a list of quote IDs to a collection
+func (h *Handlers) collectionQuotesHandler(w http.ResponseWriter, r *http.Request, apply func(*queries.CollectionQueries, context.Context, string, []int) (queries.Collection, error), logPrefix string) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var request CollectionQuotesRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxWriteBodyBytes)).Decode(&request); err != nil {
+		http.Error(w, `{"error": "Invalid JSON body"}`, http.StatusBadRequest)
+		return
+	}
+
+	collection, err := apply(h.collectionsFor(r), r.Context(), r.PathValue("slug"), request.QuoteIDs)
+	if err != nil {
+		writeCollectionWriteError(w, err, logPrefix)
+		return
+	}
+
+	json.NewEncoder(w).Encode(collection)
+}
+
+func (h *Handlers) RemoveCollectionQuoteHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		http.Error(w, `{"error": "Invalid quote id"}`, http.StatusBadRequest)
+		return
+	}
+
+	collection, err := h.collectionsFor(r).RemoveQuote(r.Context(), r.PathValue("slug"), id)
+	if err != nil {
+		writeCollectionWriteError(w, err, "Collection remove failed")
+		return
+	}
+
+	json.NewEncoder(w).Encode(collection)
+}
+
+func (h *Handlers) PublishCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	h.setCollectionPublished(w, r, true)
+}
+
+func (h *Handlers) UnpublishCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	h.setCollectionPublished(w, r, false)
+}
+
+func (h *Handlers) setCollectionPublished(w http.ResponseWriter, r *http.Request, published bool) {
+	w.Header().Set("Content-Type", "application/json")
+
+	collection, err := h.collections.SetPublished(r.Context(), r.PathValue("slug"), published)
+	if err != nil {
+		writeCollectionWriteError(w, err, "Collection publish failed")
+		return
+	}
+
+	json.NewEncoder(w).Encode(collection)
+}
+
+// writeCollectionWriteError maps collection write errors to 400, 404, 409 or 500 responses
+func writeCollectionWriteError(w http.ResponseWriter, err error, logPrefix string) {
+	var validationErr *queries.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ValidationErrorResponse{
+			Error:   "Invalid collection",
+			Field:   validationErr.Field,
+			Message: validationErr.Message,
+		})
+	case errors.Is(err, queries.ErrCollectionNotFound):
+		http.Error(w, `{"error": "Collection not found"}`, http.StatusNotFound)
+	case errors.Is(err, queries.ErrQuoteNotFound):
+		http.Error(w, `{"error": "Quote is not in this collection"}`, http.StatusNotFound)
+	case errors.Is(err, queries.ErrDuplicateCollection):
+		http.Error(w, `{"error": "A collection with this slug already exists"}`, http.StatusConflict)
+	default:
+		log.Printf("%s: %v", logPrefix, err)
+		http.Error(w, `{"error": "Database query failed"}`, http.StatusInternalServerError)
+	}
+}
+
+func (h *Handlers) ThemesHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	
-	// Get search query from URL parameter (can be empty for browse mode)
-	query := strings.TrimSpace(r.URL.Query().Get("q"))
-	
-	// Parse all browse parameters (including filters)
-	params, err := h.parseBrowseParams(r)
+
+	params := queries.ThemeListParams{Page: 1, Limit: 20}
+	params.Page, params.Limit = parsePaging(r, params.Page, params.Limit)
+
+	ctx, cancel := h.queryContext(r)
+	defer cancel()
+
+	response, err := h.themes.ListThemes(ctx, params)
 	if err != nil {
-		log.Printf("Invalid search parameters: %v", err)
+		writeDatabaseError(w, ctx, err, "Theme list query failed", `{"error": "Database query failed"}`)
+		return
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// ThemeHandler evaluates a theme, returning its definition and the current
+// page of its results
+func (h *Handlers) ThemeHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// Paging, facets and debug come from the request; the filters from the theme
+	paging, err := h.parseBrowseParams(r)
+	if err != nil {
+		log.Printf("Invalid theme parameters: %v", err)
 		http.Error(w, `{"error": "Invalid parameters"}`, http.StatusBadRequest)
 		return
 	}
+	if r.URL.Query().Get("limit") == "" {
+		// Let the theme's own page size apply
+		paging.Limit = 0
+	}
 
-	if query == "" {
-		// Browse mode: no search query, use browse logic
-		rows, err := h.browseQueries.BuildStatement(params)
-		if err != nil {
-			log.Printf("Browse query failed: %v", err)
-			http.Error(w, `{"error": "Database query failed"}`, http.StatusInternalServerError)
+	ctx, cancel := h.queryContext(r)
+	defer cancel()
+
+	response, err := h.themes.Evaluate(ctx, r.PathValue("slug"), paging)
+	if errors.Is(err, queries.ErrThemeNotFound) {
+		http.Error(w, `{"error": "Theme not found"}`, http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, queries.ErrInvalidCursor) {
+		http.Error(w, `{"error": "Invalid cursor"}`, http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, queries.ErrSemanticUnavailable) {
+		http.Error(w, `{"error": "Semantic search is not configured"}`, http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil {
+		writeDatabaseError(w, ctx, err, "Theme query failed", `{"error": "Database query failed"}`)
+		return
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) CreateThemeHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var input queries.ThemeInput
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxWriteBodyBytes)).Decode(&input); err != nil {
+		http.Error(w, `{"error": "Invalid JSON body"}`, http.StatusBadRequest)
+		return
+	}
+
+	theme, err := h.themes.CreateTheme(r.Context(), input)
+	if err != nil {
+		writeThemeWriteError(w, err, "Theme create failed")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(theme)
+}
+
+func (h *Handlers) UpdateThemeHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var input queries.ThemeInput
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxWriteBodyBytes)).Decode(&input); err != nil {
+		http.Error(w, `{"error": "Invalid JSON body"}`, http.StatusBadRequest)
+		return
+	}
+
+	theme, err := h.themes.UpdateTheme(r.Context(), r.PathValue("slug"), input)
+	if err != nil {
+		writeThemeWriteError(w, err, "Theme update failed")
+		return
+	}
+
+	json.NewEncoder(w).Encode(theme)
+}
+
+func (h *Handlers) DeleteThemeHandler(w http.ResponseWriter, r *http.Request) {
+	if err := h.themes.DeleteTheme(r.Context(), r.PathValue("slug")); err != nil {
+		writeThemeWriteError(w, err, "Theme delete failed")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SnapshotThemeHandler freezes a theme's current results into a new collection
+func (h *Handlers) SnapshotThemeHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var input queries.ThemeSnapshotInput
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxWriteBodyBytes)).Decode(&input); err != nil {
+			http.Error(w, `{"error": "Invalid JSON body"}`, http.StatusBadRequest)
 			return
 		}
-		defer rows.Close()
+	}
+
+	// The snapshot runs the theme's full search, so it is bounded like one
+	ctx, cancel := h.queryContext(r)
+	defer cancel()
+
+	collection, err := h.themes.Snapshot(ctx, r.PathValue("slug"), input)
+	if errors.Is(err, queries.ErrSemanticUnavailable) {
+		http.Error(w, `{"error": "Semantic search is not configured"}`, http.StatusServiceUnavailable)
+		return
+	}
+	if errors.Is(err, queries.ErrThemeNotFound) {
+		http.Error(w, `{"error": "Theme not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil && ctx.Err() != nil {
+		writeDatabaseError(w, ctx, err, "Theme snapshot failed", `{"error": "Database query failed"}`)
+		return
+	}
+	if err != nil {
+		writeCollectionWriteError(w, err, "Theme snapshot failed")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(collection)
+}
+
+// writeThemeWriteError maps theme write errors to 400, 404, 409 or 500 responses
+func writeThemeWriteError(w http.ResponseWriter, err error, logPrefix string) {
+	var validationErr *queries.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ValidationErrorResponse{
+			Error:   "Invalid theme",
+			Field:   validationErr.Field,
+			Message: validationErr.Message,
+		})
+	case errors.Is(err, queries.ErrThemeNotFound):
+		http.Error(w, `{"error": "Theme not found"}`, http.StatusNotFound)
+	case errors.Is(err, queries.ErrDuplicateTheme):
+		http.Error(w, `{"error": "A theme with this slug already exists"}`, http.StatusConflict)
+	default:
+		log.Printf("%s: %v", logPrefix, err)
+		http.Error(w, `{"error": "Database query failed"}`, http.StatusInternalServerError)
+	}
+}
+
+// QuoteOfTheDayHandler returns the quote picked for a date, today in the
+// requested timezone unless date is given
+func (h *Handlers) QuoteOfTheDayHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	filter, loc, today, err := parseDailyQuoteRequest(r)
+	if err != nil {
+		http.Error(w, `{"error": "Invalid timezone"}`, http.StatusBadRequest)
+		return
+	}
 
-		// Build and send browse response
-		response, err := h.browseQueries.BuildResponse(rows, params)
+	date := today
+	if dateStr := r.URL.Query().Get("date"); dateStr != "" {
+		date, err = time.Parse(time.DateOnly, dateStr)
 		if err != nil {
-			log.Printf("Browse response failed: %v", err)
-			http.Error(w, `{"error": "Failed to parse results"}`, http.StatusInternalServerError)
+			http.Error(w, `{"error": "Invalid date"}`, http.StatusBadRequest)
 			return
 		}
-
-		json.NewEncoder(w).Encode(response)
-	} else {
-		// Search mode: use search with filters
-		rows, err := h.searchQueries.BuildStatementWithFilters(query, params)
-		if err != nil {
-			log.Printf("Search with filters query failed: %v", err)
-			http.Error(w, `{"error": "Database query failed"}`, http.StatusInternalServerError)
+		if date.After(today) {
+			http.Error(w, `{"error": "Date must not be in the future"}`, http.StatusBadRequest)
 			return
 		}
-		defer rows.Close()
+	}
 
-		// Build and send search response with filters
-		response, err := h.searchQueries.BuildResponseWithFilters(rows, query, params)
-		if err != nil {
-			log.Printf("Search with filters response failed: %v", err)
-			http.Error(w, `{"error": "Failed to parse results"}`, http.StatusInternalServerError)
-			return
+	ctx, cancel := h.queryContext(r)
+	defer cancel()
+
+	pick, err := h.dailyQuotes.Pick(ctx, date, today, filter)
+	var validationErr *queries.ValidationError
+	if errors.As(err, &validationErr) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ValidationErrorResponse{
+			Error:   "Invalid filters",
+			Field:   validationErr.Field,
+			Message: validationErr.Message,
+		})
+		return
+	}
+	if errors.Is(err, queries.ErrDailyQuoteTooOld) {
+		http.Error(w, `{"error": "Date is too far in the past"}`, http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, queries.ErrNoDailyQuote) {
+		http.Error(w, `{"error": "No quotes match these filters"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		writeDatabaseError(w, ctx, err, "Quote of the day query failed", `{"error": "Database query failed"}`)
+		return
+	}
+	pick.Timezone = loc.String()
+
+	json.NewEncoder(w).Encode(pick)
+}
+
+// QuoteOfTheDayHistoryHandler lists past picks for a filter set, most recent first
+func (h *Handlers) QuoteOfTheDayHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	filter, _, today, err := parseDailyQuoteRequest(r)
+	if err != nil {
+		http.Error(w, `{"error": "Invalid timezone"}`, http.StatusBadRequest)
+		return
+	}
+	page, limit := parsePaging(r, 1, 30)
+
+	ctx, cancel := h.queryContext(r)
+	defer cancel()
+
+	history, err := h.dailyQuotes.History(ctx, filter, today, page, limit)
+	if err != nil {
+		writeDatabaseError(w, ctx, err, "Quote of the day history query failed", `{"error": "Database query failed"}`)
+		return
+	}
+
+	json.NewEncoder(w).Encode(history)
+}
+
+// parseDailyQuoteRequest reads the category and tag filters and the tz
+// parameter, an IANA zone name defaulting to UTC. today is the current date
+// in that zone, at midnight UTC.
+func parseDailyQuoteRequest(r *http.Request) (queries.DailyQuoteFilter, *time.Location, time.Time, error) {
+	filter := queries.DailyQuoteFilter{
+		Category: strings.TrimSpace(r.URL.Query().Get("category")),
+		Tag:      strings.TrimSpace(r.URL.Query().Get("tag")),
+	}
+
+	loc := time.UTC
+	if tz := r.URL.Query().Get("tz"); tz != "" {
+		var err error
+		if loc, err = time.LoadLocation(tz); err != nil {
+			return queries.DailyQuoteFilter{}, nil, time.Time{}, err
 		}
+	}
 
-		json.NewEncoder(w).Encode(response)
+	year, month, day := time.Now().In(loc).Date()
+	return filter, loc, time.Date(year, month, day, 0, 0, 0, 0, time.UTC), nil
+}
+
+func (h *Handlers) RelatedHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		http.Error(w, `{"error": "Invalid quote id"}`, http.StatusBadRequest)
+		return
+	}
+
+	// Related quotes accept the same filters as browse
+	params, err := h.parseBrowseParams(r)
+	if err != nil {
+		log.Printf("Invalid related parameters: %v", err)
+		http.Error(w, `{"error": "Invalid parameters"}`, http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := h.queryContext(r)
+	defer cancel()
+
+	response, err := h.searchQueries.GetRelated(ctx, id, params)
+	if errors.Is(err, queries.ErrQuoteNotFound) {
+		http.Error(w, `{"error": "Quote not found"}`, http.StatusNotFound)
+		return
 	}
+	if err != nil {
+		writeDatabaseError(w, ctx, err, "Related query failed", `{"error": "Database query failed"}`)
+		return
+	}
+
+	json.NewEncoder(w).Encode(response)
 }
 
-func (h *Handlers) BrowseHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) AuthorsHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	
-	// Parse query parameters into BrowseParams
+
+	params := queries.AuthorListParams{
+		Page:   1,
+		Limit:  20,
+		Sort:   r.URL.Query().Get("sort"),
+		Order:  r.URL.Query().Get("order"),
+		Prefix: r.URL.Query().Get("prefix"),
+	}
+	params.Page, params.Limit = parsePaging(r, params.Page, params.Limit)
+
+	ctx, cancel := h.queryContext(r)
+	defer cancel()
+
+	response, err := h.authorQueries.ListAuthors(ctx, params)
+	if err != nil {
+		writeDatabaseError(w, ctx, err, "Author list query failed", `{"error": "Database query failed"}`)
+		return
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) AuthorHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// Author quotes accept the same paging, sorting and filters as browse
 	params, err := h.parseBrowseParams(r)
 	if err != nil {
-		log.Printf("Invalid browse parameters: %v", err)
+		log.Printf("Invalid author parameters: %v", err)
 		http.Error(w, `{"error": "Invalid parameters"}`, http.StatusBadRequest)
 		return
 	}
 
-	// Execute database query
-	rows, err := h.browseQueries.BuildStatement(params)
+	ctx, cancel := h.queryContext(r)
+	defer cancel()
+
+	profile, err := h.authorQueries.GetAuthor(ctx, r.PathValue("slug"), params)
+	if errors.Is(err, queries.ErrAuthorNotFound) {
+		http.Error(w, `{"error": "Author not found"}`, http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, queries.ErrInvalidCursor) {
+		http.Error(w, `{"error": "Invalid cursor"}`, http.StatusBadRequest)
+		return
+	}
 	if err != nil {
-		log.Printf("Browse query failed: %v", err)
-		http.Error(w, `{"error": "Database query failed"}`, http.StatusInternalServerError)
+		writeDatabaseError(w, ctx, err, "Author profile query failed", `{"error": "Database query failed"}`)
+		return
+	}
+
+	json.NewEncoder(w).Encode(profile)
+}
+
+func (h *Handlers) CategoriesHandler(w http.ResponseWriter, r *http.Request) {
+	h.catalogueHandler(w, r, h.catalogue.ListCategories)
+}
+
+func (h *Handlers) TagsHandler(w http.ResponseWriter, r *http.Request) {
+	h.catalogueHandler(w, r, h.catalogue.ListTags)
+}
+
+func (h *Handlers) catalogueHandler(w http.ResponseWriter, r *http.Request, list func(context.Context, queries.CatalogueParams) (queries.CatalogueResponse, error)) {
+	w.Header().Set("Content-Type", "application/json")
+
+	params := queries.CatalogueParams{
+		Page:   1,
+		Limit:  50,
+		Prefix: strings.TrimSpace(r.URL.Query().Get("prefix")),
+	}
+	params.Page, params.Limit = parsePaging(r, params.Page, params.Limit)
+
+	ctx, cancel := h.queryContext(r)
+	defer cancel()
+
+	response, err := list(ctx, params)
+	if err != nil {
+		writeDatabaseError(w, ctx, err, "Catalogue query failed", `{"error": "Database query failed"}`)
 		return
 	}
-	defer rows.Close()
 
-	// Build and send response
-	response, err := h.browseQueries.BuildResponse(rows, params)
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) RelatedTagsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	_, limit := parsePaging(r, 1, 10)
+
+	ctx, cancel := h.queryContext(r)
+	defer cancel()
+
+	response, err := h.catalogue.RelatedTags(ctx, r.PathValue("tag"), limit)
+	if errors.Is(err, queries.ErrTagNotFound) {
+		http.Error(w, `{"error": "Tag not found"}`, http.StatusNotFound)
+		return
+	}
 	if err != nil {
-		log.Printf("Browse response failed: %v", err)
-		http.Error(w, `{"error": "Failed to parse results"}`, http.StatusInternalServerError)
+		writeDatabaseError(w, ctx, err, "Related tags query failed", `{"error": "Database query failed"}`)
 		return
 	}
 
 	json.NewEncoder(w).Encode(response)
 }
 
+// exportFlushEvery is how many quotes are written between response flushes
+const exportFlushEvery = 200
+
+// ExportHandler streams every quote matching the browse filters and optional
+// q as NDJSON, CSV or a JSON array. JSON and NDJSON use the ingestion format.
+func (h *Handlers) ExportHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	params, err := h.parseBrowseParams(r)
+	if err != nil {
+		log.Printf("Invalid export parameters: %v", err)
+		http.Error(w, `{"error": "Invalid parameters"}`, http.StatusBadRequest)
+		return
+	}
+
+	var parsed *queries.ParsedQuery
+	if query := strings.TrimSpace(r.URL.Query().Get("q")); query != "" {
+		parsed, err = queries.ParseQuery(query)
+		if err != nil {
+			var parseErr *queries.QueryParseError
+			if errors.As(err, &parseErr) {
+				writeQueryError(w, parseErr)
+				return
+			}
+			http.Error(w, `{"error": "Invalid query"}`, http.StatusBadRequest)
+			return
+		}
+	}
+
+	encoder, err := newExportEncoder(r.URL.Query().Get("format"), w)
+	if err != nil {
+		http.Error(w, `{"error": "format must be ndjson, csv or json"}`, http.StatusBadRequest)
+		return
+	}
+
+	// Headers are sent with the first quote so that a query that fails
+	// before producing rows can still return a 500
+	controller := http.NewResponseController(w)
+	started := false
+	begin := func() error {
+		started = true
+		w.Header().Set("Content-Type", encoder.ContentType())
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="quotes.%s"`, encoder.Extension()))
+		return encoder.Begin()
+	}
+
+	// An export streams every matching quote, so it gets a longer deadline
+	// than a page
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.ExportTimeout)
+	defer cancel()
+
+	written := 0
+	err = h.exportQueries.Export(ctx, parsed, params, func(q queries.Quote) error {
+		if !started {
+			if err := begin(); err != nil {
+				return err
+			}
+		}
+		if err := encoder.Encode(q); err != nil {
+			return err
+		}
+		written++
+		if written%exportFlushEvery == 0 {
+			if err := encoder.Flush(); err != nil {
+				return err
+			}
+			return controller.Flush()
+		}
+		return nil
+	})
+	if err == nil && !started {
+		err = begin()
+	}
+	if err != nil {
+		if !started {
+			writeDatabaseError(w, ctx, err, "Export query failed", `{"error": "Database query failed"}`)
+			return
+		}
+		// The status line is already sent; a truncated body signals the failure
+		log.Printf("Export aborted after %d quotes: %v", written, err)
+		return
+	}
+
+	if err := encoder.End(); err != nil {
+		log.Printf("Export failed to finish: %v", err)
+	}
+}
+
+// parsePaging reads page and limit, keeping the defaults for missing or invalid values
+func parsePaging(r *http.Request, page, limit int) (int, int) {
+	if pageStr := r.URL.Query().Get("page"); pageStr != "" {
+		if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
+			page = p
+		}
+	}
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+	return page, limit
+}
+
 func (h *Handlers) parseBrowseParams(r *http.Request) (queries.BrowseParams, error) {
 	params := queries.BrowseParams{
 		Page:          1,
 		Limit:         20,
-		Sort:          "popularity",
 		Order:         "desc",
 		IncludeFacets: true,
 		FacetLimit:    10,
@@ -151,10 +1354,11 @@ func (h *Handlers) parseBrowseParams(r *http.Request) (queries.BrowseParams, err
 		}
 	}
 
-	// Parse sort
-	if sort := r.URL.Query().Get("sort"); sort != "" {
-		params.Sort = sort
-	}
+	// Parse keyset cursor; when set it takes precedence over page
+	params.Cursor = r.URL.Query().Get("cursor")
+
+	// Parse sort; browse defaults to popularity and search to relevance
+	params.Sort = r.URL.Query().Get("sort")
 
 	// Parse order
 	if order := r.URL.Query().Get("order"); order != "" {
@@ -201,5 +1405,60 @@ func (h *Handlers) parseBrowseParams(r *http.Request) (queries.BrowseParams, err
 		}
 	}
 
+	// Parse fuzzy mode: "auto" (default) falls back to fuzzy matching when
+	// nothing matches exactly, 0 disables it and 1 or 2 force a max edit distance
+	if fuzzyStr := r.URL.Query().Get("fuzzy"); fuzzyStr != "" && fuzzyStr != "auto" {
+		fuzzy, err := strconv.Atoi(fuzzyStr)
+		if err != nil || fuzzy < 0 || fuzzy > queries.MaxFuzzyDistance {
+			return params, fmt.Errorf("invalid fuzzy %q: use auto or 0 to %d", fuzzyStr, queries.MaxFuzzyDistance)
+		}
+		params.Fuzzy = &fuzzy
+	}
+
+	// Parse search mode: keyword (default), semantic or hybrid
+	switch mode := r.URL.Query().Get("mode"); mode {
+	case queries.ModeKeyword, queries.ModeSemantic, queries.ModeHybrid:
+		params.Mode = mode
+	}
+
+	// Parse random order seed, which makes sort=random stable across pages
+	if seedStr := r.URL.Query().Get("seed"); seedStr != "" {
+		if seed, err := strconv.ParseInt(seedStr, 10, 64); err == nil {
+			params.Seed = &seed
+		}
+	}
+
+	// Parse debug flag, which adds score components to search results
+	if debugStr := r.URL.Query().Get("debug"); debugStr != "" {
+		if debug, err := strconv.ParseBool(debugStr); err == nil {
+			params.Debug = debug
+		}
+	}
+
+	// Parse per-request field boost overrides
+	boosts := h.config.Search.Boosts
+	overridden := false
+	for name, target := range map[string]*float64{
+		"boost_quote":    &boosts.Quote,
+		"boost_author":   &boosts.Author,
+		"boost_tags":     &boosts.Tags,
+		"boost_category": &boosts.Category,
+	} {
+		if boostStr := r.URL.Query().Get(name); boostStr != "" {
+			boost, err := strconv.ParseFloat(boostStr, 64)
+			if err != nil {
+				return params, fmt.Errorf("invalid %s %q", name, boostStr)
+			}
+			*target = boost
+			overridden = true
+		}
+	}
+	if overridden {
+		if !boosts.Valid() {
+			return params, errors.New("field boosts must be finite and non-negative, and at least one must be positive")
+		}
+		params.Boosts = &boosts
+	}
+
 	return params, nil
 }
\ No newline at end of file
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"quotes-api/queries"
+)
+
+func TestParseBrowseParamsBoosts(t *testing.T) {
+	h := &Handlers{config: Config{Search: queries.DefaultSearchConfig()}}
+
+	params, err := h.parseBrowseParams(httptest.NewRequest("GET", "/api/search?q=x&boost_author=5", nil))
+	if err != nil || params.Boosts == nil || params.Boosts.Author != 5 || params.Boosts.Quote != queries.DefaultSearchBoosts().Quote {
+		t.Errorf("boost_author=5: params.Boosts = %+v, err = %v", params.Boosts, err)
+	}
+
+	for _, query := range []string{
+		"boost_quote=inf",
+		"boost_quote=NaN",
+		"boost_author=-1",
+		"boost_tags=x",
+		"boost_quote=0&boost_author=0&boost_tags=0&boost_category=0",
+	} {
+		if _, err := h.parseBrowseParams(httptest.NewRequest("GET", "/api/search?q=x&"+query, nil)); err == nil {
+			t.Errorf("%s: parseBrowseParams accepted the boosts", query)
+		}
+	}
+}
+
+func TestParseBrowseParamsFuzzy(t *testing.T) {
+	h := &Handlers{config: Config{Search: queries.DefaultSearchConfig()}}
+
+	for query, want := range map[string]int{"fuzzy=0": 0, "fuzzy=2": 2} {
+		params, err := h.parseBrowseParams(httptest.NewRequest("GET", "/api/search?q=x&"+query, nil))
+		if err != nil || params.Fuzzy == nil || *params.Fuzzy != want {
+			t.Errorf("%s: params.Fuzzy = %v, err = %v", query, params.Fuzzy, err)
+		}
+	}
+	if params, err := h.parseBrowseParams(httptest.NewRequest("GET", "/api/search?q=x&fuzzy=auto", nil)); err != nil || params.Fuzzy != nil {
+		t.Errorf("fuzzy=auto: params.Fuzzy = %v, err = %v", params.Fuzzy, err)
+	}
+
+	for _, query := range []string{"fuzzy=9", "fuzzy=-1", "fuzzy=abc"} {
+		if _, err := h.parseBrowseParams(httptest.NewRequest("GET", "/api/search?q=x&"+query, nil)); err == nil {
+			t.Errorf("%s: parseBrowseParams accepted the fuzzy mode", query)
+		}
+	}
+}
@@ -4,11 +4,11 @@ import (
 	"context"
 	"log"
 	"net/http"
-	"os"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/joho/godotenv"
 	"github.com/rs/cors"
+	"quotes-api/queries"
 )
 
 func main() {
@@ -17,14 +17,14 @@ func main() {
 		log.Printf("Warning: Could not load .env file: %v", err)
 	}
 
-	// Load database URL from environment
-	databaseURL := os.Getenv("DATABASE_URL")
-	if databaseURL == "" {
-		log.Fatal("DATABASE_URL environment variable is required")
+	// Load server configuration from environment
+	config, err := LoadConfig()
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
 	}
 
 	// Create database connection pool
-	pool, err := pgxpool.New(context.Background(), databaseURL)
+	pool, err := pgxpool.New(context.Background(), config.DatabaseURL)
 	if err != nil {
 		log.Fatalf("Failed to create connection pool: %v", err)
 	}
@@ -35,31 +35,101 @@ func main() {
 		log.Fatalf("Failed to ping database: %v", err)
 	}
 
+	// Warm in-memory search indexes and keep them fresh
+	spelling := queries.NewSpellingIndex(pool)
+	autocomplete := queries.NewAutocompleteIndex(pool)
+	for name, refresh := range map[string]func(context.Context) error{
+		"Spelling":     spelling.Refresh,
+		"Autocomplete": autocomplete.Refresh,
+	} {
+		if err := refresh(context.Background()); err != nil {
+			log.Printf("Warning: Could not load %s index: %v", name, err)
+		}
+		go queries.RefreshPeriodically(context.Background(), name, config.IndexRefreshInterval, refresh)
+	}
+
+	// Embed new and edited quotes in the background for semantic search
+	embedder, err := queries.NewEmbedder(config.Embedding)
+	if err != nil {
+		log.Fatalf("Invalid embedding configuration: %v", err)
+	}
+	embeddings := queries.NewEmbeddingQueries(pool, embedder)
+	go func() {
+		if err := embeddings.Refresh(context.Background()); err != nil {
+			log.Printf("Warning: Could not embed quotes: %v", err)
+		}
+		queries.RefreshPeriodically(context.Background(), "Embeddings", config.IndexRefreshInterval, embeddings.Refresh)
+	}()
+
 	// Create handlers
-	handlers := NewHandlers(pool)
+	handlers := NewHandlers(pool, config, spelling, autocomplete, embedder)
 
 	// Setup routes
 	mux := http.NewServeMux()
 	mux.HandleFunc("/health", handlers.HealthHandler)
 	mux.HandleFunc("/api/search", handlers.SearchHandler)
+	mux.HandleFunc("GET /api/search/explain", handlers.RequireAdmin(handlers.SearchExplainHandler))
 	mux.HandleFunc("/api/browse", handlers.BrowseHandler)
+	mux.HandleFunc("/api/suggest/spelling", handlers.SpellingHandler)
+	mux.HandleFunc("/api/autocomplete", handlers.AutocompleteHandler)
+	mux.HandleFunc("GET /api/export", handlers.ExportHandler)
+	mux.HandleFunc("GET /api/quotes", handlers.QuoteBatchHandler)
+	mux.HandleFunc("GET /api/quotes/{id}", handlers.QuoteHandler)
+	mux.HandleFunc("POST /api/quotes", handlers.RequireAdmin(handlers.CreateQuoteHandler))
+	mux.HandleFunc("PATCH /api/quotes/{id}", handlers.RequireAdmin(handlers.UpdateQuoteHandler))
+	mux.HandleFunc("DELETE /api/quotes/{id}", handlers.RequireAdmin(handlers.DeleteQuoteHandler))
+	mux.HandleFunc("GET /api/quotes/{id}/related", handlers.RelatedHandler)
+	mux.HandleFunc("GET /api/collections", handlers.CollectionsHandler)
+	mux.HandleFunc("GET /api/collections/{slug}", handlers.CollectionHandler)
+	mux.HandleFunc("GET /api/admin/collections", handlers.RequireAdmin(handlers.CollectionsHandler))
+	mux.HandleFunc("GET /api/admin/collections/{slug}", handlers.RequireAdmin(handlers.CollectionHandler))
+	mux.HandleFunc("POST /api/collections", handlers.RequireAdmin(handlers.CreateCollectionHandler))
+	mux.HandleFunc("PATCH /api/collections/{slug}", handlers.RequireAdmin(handlers.UpdateCollectionHandler))
+	mux.HandleFunc("DELETE /api/collections/{slug}", handlers.RequireAdmin(handlers.DeleteCollectionHandler))
+	mux.HandleFunc("POST /api/collections/{slug}/quotes", handlers.RequireAdmin(handlers.AddCollectionQuotesHandler))
+	mux.HandleFunc("PUT /api/collections/{slug}/quotes", handlers.RequireAdmin(handlers.ReorderCollectionQuotesHandler))
+	mux.HandleFunc("DELETE /api/collections/{slug}/quotes/{id}", handlers.RequireAdmin(handlers.RemoveCollectionQuoteHandler))
+	mux.HandleFunc("POST /api/collections/{slug}/publish", handlers.RequireAdmin(handlers.PublishCollectionHandler))
+	mux.HandleFunc("DELETE /api/collections/{slug}/publish", handlers.RequireAdmin(handlers.UnpublishCollectionHandler))
+	mux.HandleFunc("POST /api/users", handlers.RequireAdmin(handlers.CreateUserHandler))
+	mux.HandleFunc("GET /api/me", handlers.RequireUser(handlers.MeHandler))
+	mux.HandleFunc("GET /api/me/favorites", handlers.RequireUser(handlers.FavoritesHandler))
+	mux.HandleFunc("PUT /api/me/favorites/{id}", handlers.RequireUser(handlers.AddFavoriteHandler))
+	mux.HandleFunc("DELETE /api/me/favorites/{id}", handlers.RequireUser(handlers.RemoveFavoriteHandler))
+	mux.HandleFunc("GET /api/me/collections", handlers.RequireUser(handlers.CollectionsHandler))
+	mux.HandleFunc("GET /api/me/collections/{slug}", handlers.RequireUser(handlers.CollectionHandler))
+	mux.HandleFunc("POST /api/me/collections", handlers.RequireUser(handlers.CreateCollectionHandler))
+	mux.HandleFunc("PATCH /api/me/collections/{slug}", handlers.RequireUser(handlers.UpdateCollectionHandler))
+	mux.HandleFunc("DELETE /api/me/collections/{slug}", handlers.RequireUser(handlers.DeleteCollectionHandler))
+	mux.HandleFunc("POST /api/me/collections/{slug}/quotes", handlers.RequireUser(handlers.AddCollectionQuotesHandler))
+	mux.HandleFunc("PUT /api/me/collections/{slug}/quotes", handlers.RequireUser(handlers.ReorderCollectionQuotesHandler))
+	mux.HandleFunc("DELETE /api/me/collections/{slug}/quotes/{id}", handlers.RequireUser(handlers.RemoveCollectionQuoteHandler))
+	mux.HandleFunc("GET /api/themes", handlers.ThemesHandler)
+	mux.HandleFunc("GET /api/themes/{slug}", handlers.ThemeHandler)
+	mux.HandleFunc("POST /api/themes", handlers.RequireAdmin(handlers.CreateThemeHandler))
+	mux.HandleFunc("PATCH /api/themes/{slug}", handlers.RequireAdmin(handlers.UpdateThemeHandler))
+	mux.HandleFunc("DELETE /api/themes/{slug}", handlers.RequireAdmin(handlers.DeleteThemeHandler))
+	mux.HandleFunc("POST /api/themes/{slug}/snapshot", handlers.RequireAdmin(handlers.SnapshotThemeHandler))
+	mux.HandleFunc("GET /api/quote-of-the-day", handlers.QuoteOfTheDayHandler)
+	mux.HandleFunc("GET /api/quote-of-the-day/history", handlers.QuoteOfTheDayHistoryHandler)
+	mux.HandleFunc("GET /api/authors", handlers.AuthorsHandler)
+	mux.HandleFunc("GET /api/authors/{slug}", handlers.AuthorHandler)
+	mux.HandleFunc("GET /api/categories", handlers.CategoriesHandler)
+	mux.HandleFunc("GET /api/tags", handlers.TagsHandler)
+	mux.HandleFunc("GET /api/tags/{tag}/related", handlers.RelatedTagsHandler)
 
 	// Setup CORS
 	c := cors.New(cors.Options{
 		AllowedOrigins: []string{"*"},
-		AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowedHeaders: []string{"*"},
 	})
 
 	// Start server
 	handler := c.Handler(mux)
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
-	}
 
-	log.Printf("Server starting on port %s", port)
-	if err := http.ListenAndServe(":"+port, handler); err != nil {
+	log.Printf("Server starting on port %s", config.Port)
+	if err := http.ListenAndServe(":"+config.Port, handler); err != nil {
 		log.Fatalf("Server failed to start: %v", err)
 	}
 }
\ No newline at end of file
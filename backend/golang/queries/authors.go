@@ -0,0 +1,227 @@
+package queries
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrAuthorNotFound is returned when no quotes belong to an author slug
+var ErrAuthorNotFound = errors.New("author not found")
+
+// authorSlugSQL derives the author slug in SQL. It must stay in step with
+// AuthorSlug and matches the idx_quotes_author_slug and
+// idx_quotes_author_slug_pattern expression indexes.
+const authorSlugSQL = `trim(both '-' from regexp_replace(lower(author), '[^[:alnum:]]+', '-', 'g'))`
+
+// AuthorSlug returns the stable URL slug for an author name. Case and
+// punctuation are ignored, so "Dr. Seuss" and "Dr Seuss" share "dr-seuss".
+func AuthorSlug(name string) string {
+	return slugify(name)
+}
+
+// slugify lowercases s and joins its runs of letters and digits with dashes
+func slugify(s string) string {
+	var b strings.Builder
+	pendingDash := false
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if pendingDash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			pendingDash = false
+			b.WriteRune(r)
+			continue
+		}
+		pendingDash = true
+	}
+	return b.String()
+}
+
+// AuthorSummary represents one author in the author directory
+type AuthorSummary struct {
+	Slug          string   `json:"slug"`
+	Name          string   `json:"name"`
+	QuoteCount    int      `json:"quote_count"`
+	AvgPopularity *float64 `json:"avg_popularity,omitempty"`
+}
+
+// AuthorListParams represents parameters for the author directory
+type AuthorListParams struct {
+	Page   int    `json:"page"`
+	Limit  int    `json:"limit"`
+	Sort   string `json:"sort"`
+	Order  string `json:"order"`
+	Prefix string `json:"prefix"`
+}
+
+// AuthorListResponse represents the response for author directory API
+type AuthorListResponse struct {
+	Authors    []AuthorSummary `json:"authors"`
+	Pagination Pagination      `json:"pagination"`
+}
+
+// PopularityStats summarises the popularity of a set of quotes
+type PopularityStats struct {
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+	Avg float64 `json:"avg"`
+}
+
+// AuthorProfile represents the response for author profile API
+type AuthorProfile struct {
+	Slug          string           `json:"slug"`
+	Name          string           `json:"name"`
+	Variants      []string         `json:"variants"`
+	QuoteCount    int              `json:"quote_count"`
+	Popularity    *PopularityStats `json:"popularity,omitempty"`
+	TopCategories []FacetItem      `json:"top_categories"`
+	TopTags       []FacetItem      `json:"top_tags"`
+	Quotes        BrowseResponse   `json:"quotes"`
+}
+
+type AuthorQueries struct {
+	db     *pgxpool.Pool
+	browse *BrowseQueries
+}
+
+func NewAuthorQueries(db *pgxpool.Pool, browse *BrowseQueries) *AuthorQueries {
+	return &AuthorQueries{db: db, browse: browse}
+}
+
+// ListAuthors returns a page of authors grouped by slug
+func (aq *AuthorQueries) ListAuthors(ctx context.Context, params AuthorListParams) (AuthorListResponse, error) {
+	whereClause, args := aq.buildPrefixClause(params.Prefix)
+	orderBy := aq.buildOrderBy(params.Sort, params.Order)
+	offset := (params.Page - 1) * params.Limit
+
+	sql := fmt.Sprintf(`
+		SELECT %s AS slug,
+		       mode() WITHIN GROUP (ORDER BY author) AS name,
+		       COUNT(*) AS quote_count,
+		       AVG(popularity) AS avg_popularity
+		FROM quotes
+		%s
+		GROUP BY slug
+		%s
+		LIMIT $%d OFFSET $%d
+	`, authorSlugSQL, whereClause, orderBy, len(args)+1, len(args)+2)
+
+	rows, err := aq.db.Query(ctx, sql, append(args, params.Limit, offset)...)
+	if err != nil {
+		return AuthorListResponse{}, err
+	}
+	defer rows.Close()
+
+	authors := []AuthorSummary{}
+	for rows.Next() {
+		var a AuthorSummary
+		if err := rows.Scan(&a.Slug, &a.Name, &a.QuoteCount, &a.AvgPopularity); err != nil {
+			return AuthorListResponse{}, err
+		}
+		authors = append(authors, a)
+	}
+	if err := rows.Err(); err != nil {
+		return AuthorListResponse{}, err
+	}
+
+	// Get total count
+	countSQL := fmt.Sprintf(`
+		SELECT COUNT(DISTINCT %s)
+		FROM quotes
+		%s
+	`, authorSlugSQL, whereClause)
+
+	var totalCount int
+	if err := aq.db.QueryRow(ctx, countSQL, args...).Scan(&totalCount); err != nil {
+		return AuthorListResponse{}, err
+	}
+
+	return AuthorListResponse{
+		Authors:    authors,
+		Pagination: aq.browse.buildPagination(params.Page, params.Limit, totalCount),
+	}, nil
+}
+
+// GetAuthor returns an author's profile with their quotes paged and filtered
+// through browse. Returns ErrAuthorNotFound when the slug has no quotes.
+func (aq *AuthorQueries) GetAuthor(ctx context.Context, slug string, params BrowseParams) (AuthorProfile, error) {
+	slug = AuthorSlug(slug)
+
+	sql := fmt.Sprintf(`
+		SELECT mode() WITHIN GROUP (ORDER BY author),
+		       array_agg(DISTINCT author),
+		       COUNT(*),
+		       MIN(popularity), MAX(popularity), AVG(popularity)
+		FROM quotes
+		WHERE %s = $1
+	`, authorSlugSQL)
+
+	profile := AuthorProfile{Slug: slug}
+	var name *string
+	var min, max, avg *float64
+	err := aq.db.QueryRow(ctx, sql, slug).Scan(&name, &profile.Variants, &profile.QuoteCount, &min, &max, &avg)
+	if err != nil {
+		return AuthorProfile{}, err
+	}
+	if profile.QuoteCount == 0 || name == nil {
+		return AuthorProfile{}, ErrAuthorNotFound
+	}
+	profile.Name = *name
+	if min != nil && max != nil && avg != nil {
+		profile.Popularity = &PopularityStats{Min: *min, Max: *max, Avg: *avg}
+	}
+
+	// Top categories and tags across all of the author's quotes
+	topParams := BrowseParams{AuthorSlug: slug, FacetLimit: 10}
+	if profile.TopCategories, err = aq.browse.getCategoryFacets(ctx, topParams); err != nil {
+		return AuthorProfile{}, err
+	}
+	if profile.TopTags, err = aq.browse.getTagFacets(ctx, topParams); err != nil {
+		return AuthorProfile{}, err
+	}
+
+	// The author's quotes, with the caller's filters, paging and facets
+	params.AuthorSlug = slug
+	if profile.Quotes, err = aq.browse.Browse(ctx, params); err != nil {
+		return AuthorProfile{}, err
+	}
+
+	return profile, nil
+}
+
+// buildPrefixClause matches author slugs starting with prefix. A slug has no
+// LIKE wildcards, and the pattern is passed whole so the planner can serve it
+// from idx_quotes_author_slug_pattern.
+func (aq *AuthorQueries) buildPrefixClause(prefix string) (string, []interface{}) {
+	prefix = AuthorSlug(prefix)
+	if prefix == "" {
+		return "", nil
+	}
+	return fmt.Sprintf("WHERE %s LIKE $1", authorSlugSQL), []interface{}{prefix + "%"}
+}
+
+func (aq *AuthorQueries) buildOrderBy(sort, order string) string {
+	// Validate sort field
+	validSorts := map[string]string{
+		"quote_count":    "quote_count",
+		"avg_popularity": "avg_popularity",
+		"name":           "name",
+	}
+
+	sortField, exists := validSorts[sort]
+	if !exists {
+		sortField = "quote_count" // default
+	}
+
+	// Validate order
+	if order != "asc" && order != "desc" {
+		order = "desc" // default
+	}
+
+	return fmt.Sprintf("ORDER BY %s %s NULLS LAST, slug", sortField, strings.ToUpper(order))
+}
@@ -0,0 +1,33 @@
+package queries
+
+import "testing"
+
+func TestAuthorSlug(t *testing.T) {
+	tests := map[string]string{
+		"Dr. Seuss":              "dr-seuss",
+		"Dr Seuss":               "dr-seuss",
+		"  dr.seuss ":            "dr-seuss",
+		"Martin Luther King Jr.": "martin-luther-king-jr",
+		"Gabriel García Márquez": "gabriel-garcía-márquez",
+	}
+
+	for name, want := range tests {
+		if got := AuthorSlug(name); got != want {
+			t.Errorf("AuthorSlug(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestAuthorPrefixClause(t *testing.T) {
+	aq := &AuthorQueries{}
+
+	// The pattern is bound whole, so the planner sees a constant prefix
+	clause, args := aq.buildPrefixClause("Dr. Se")
+	if clause != "WHERE "+authorSlugSQL+" LIKE $1" || len(args) != 1 || args[0] != "dr-se%" {
+		t.Errorf("buildPrefixClause = %q, %v", clause, args)
+	}
+
+	if clause, args := aq.buildPrefixClause(" .. "); clause != "" || args != nil {
+		t.Errorf("buildPrefixClause of an empty slug = %q, %v", clause, args)
+	}
+}
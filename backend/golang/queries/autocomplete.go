@@ -0,0 +1,261 @@
+package queries
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Completion types returned by autocomplete
+const (
+	CompletionTag      = "tag"
+	CompletionAuthor   = "author"
+	CompletionCategory = "category"
+	CompletionQuote    = "quote"
+)
+
+// completionOrder is the order completion types are interleaved in results
+var completionOrder = []string{CompletionTag, CompletionAuthor, CompletionCategory, CompletionQuote}
+
+const (
+	// autocompleteSourceLimit caps how many values of each type are loaded
+	autocompleteSourceLimit = 20000
+	// autocompleteQuoteLimit is how many of the most popular quotes contribute openings
+	autocompleteQuoteLimit = 5000
+	// quoteOpeningWords is the length of the quote opening offered as a completion
+	quoteOpeningWords = 6
+)
+
+// Completion is a single typed autocomplete suggestion
+type Completion struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// AutocompleteResponse represents the response for autocomplete API
+type AutocompleteResponse struct {
+	Query       string       `json:"query"`
+	Completions []Completion `json:"completions"`
+}
+
+type completionEntry struct {
+	key        string
+	completion Completion
+}
+
+// AutocompleteIndex serves search-as-you-type completions from an in-memory
+// sorted prefix list of tags, authors, categories and popular quote openings.
+// It is held in memory and refreshed periodically.
+type AutocompleteIndex struct {
+	db     *pgxpool.Pool
+	browse *BrowseQueries
+
+	mu      sync.RWMutex
+	entries []completionEntry // sorted by key
+}
+
+func NewAutocompleteIndex(db *pgxpool.Pool) *AutocompleteIndex {
+	return &AutocompleteIndex{db: db, browse: NewBrowseQueries(db, DefaultSearchConfig())}
+}
+
+// Refresh reloads all completion sources from the database
+func (ai *AutocompleteIndex) Refresh(ctx context.Context) error {
+	var entries []completionEntry
+
+	// Tags and categories come from the same facet queries browse uses
+	sourceParams := BrowseParams{FacetLimit: autocompleteSourceLimit}
+	tags, err := ai.browse.getTagFacets(ctx, sourceParams)
+	if err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		entries = appendWordEntries(entries, Completion{Type: CompletionTag, Value: strings.TrimSpace(tag.Value), Count: tag.Count})
+	}
+
+	categories, err := ai.browse.getCategoryFacets(ctx, sourceParams)
+	if err != nil {
+		return err
+	}
+	for _, category := range categories {
+		entries = appendWordEntries(entries, Completion{Type: CompletionCategory, Value: category.Value, Count: category.Count})
+	}
+
+	authors, err := ai.loadAuthors(ctx)
+	if err != nil {
+		return err
+	}
+	for _, author := range authors {
+		entries = appendWordEntries(entries, Completion{Type: CompletionAuthor, Value: author.Value, Count: author.Count})
+	}
+
+	openings, err := ai.loadQuoteOpenings(ctx)
+	if err != nil {
+		return err
+	}
+	for _, opening := range openings {
+		// Quote openings only complete from their first word
+		entries = append(entries, completionEntry{
+			key:        strings.ToLower(opening.Value),
+			completion: Completion{Type: CompletionQuote, Value: opening.Value, Count: opening.Count},
+		})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].key < entries[j].key
+	})
+
+	ai.mu.Lock()
+	ai.entries = entries
+	ai.mu.Unlock()
+
+	return nil
+}
+
+func (ai *AutocompleteIndex) loadAuthors(ctx context.Context) ([]FacetItem, error) {
+	sql := `
+		SELECT author, COUNT(*) as count
+		FROM quotes
+		GROUP BY author
+		ORDER BY count DESC
+		LIMIT $1
+	`
+
+	rows, err := ai.db.Query(ctx, sql, autocompleteSourceLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var authors []FacetItem
+	for rows.Next() {
+		var item FacetItem
+		if err := rows.Scan(&item.Value, &item.Count); err != nil {
+			return nil, err
+		}
+		authors = append(authors, item)
+	}
+
+	return authors, rows.Err()
+}
+
+// loadQuoteOpenings returns the first words of the most popular quotes,
+// counting how many quotes share each opening
+func (ai *AutocompleteIndex) loadQuoteOpenings(ctx context.Context) ([]FacetItem, error) {
+	sql := `
+		SELECT quote
+		FROM quotes
+		ORDER BY popularity DESC NULLS LAST
+		LIMIT $1
+	`
+
+	rows, err := ai.db.Query(ctx, sql, autocompleteQuoteLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	var order []string
+	for rows.Next() {
+		var quote string
+		if err := rows.Scan(&quote); err != nil {
+			return nil, err
+		}
+		words := strings.Fields(quote)
+		if len(words) > quoteOpeningWords {
+			words = words[:quoteOpeningWords]
+		}
+		opening := strings.Join(words, " ")
+		if opening == "" {
+			continue
+		}
+		if counts[opening] == 0 {
+			order = append(order, opening)
+		}
+		counts[opening]++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	openings := make([]FacetItem, len(order))
+	for i, opening := range order {
+		openings[i] = FacetItem{Value: opening, Count: counts[opening]}
+	}
+	return openings, nil
+}
+
+// appendWordEntries indexes a completion under the start of every word in its
+// value, so "chur" completes "Winston Churchill"
+func appendWordEntries(entries []completionEntry, completion Completion) []completionEntry {
+	if completion.Value == "" {
+		return entries
+	}
+
+	key := strings.ToLower(completion.Value)
+	wordStart := true
+	for i, r := range key {
+		isWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if isWord && wordStart {
+			entries = append(entries, completionEntry{key: key[i:], completion: completion})
+		}
+		wordStart = !isWord
+	}
+	return entries
+}
+
+// Complete returns up to limit completions for prefix, interleaving types and
+// ordering each type by count
+func (ai *AutocompleteIndex) Complete(prefix string, limit int) []Completion {
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+	if prefix == "" || limit <= 0 {
+		return []Completion{}
+	}
+
+	ai.mu.RLock()
+	defer ai.mu.RUnlock()
+
+	// Collect matches per type, deduplicating values indexed under several words
+	byType := make(map[string][]Completion)
+	seen := make(map[Completion]bool)
+	start := sort.Search(len(ai.entries), func(i int) bool {
+		return ai.entries[i].key >= prefix
+	})
+	for i := start; i < len(ai.entries) && strings.HasPrefix(ai.entries[i].key, prefix); i++ {
+		completion := ai.entries[i].completion
+		if seen[completion] {
+			continue
+		}
+		seen[completion] = true
+		byType[completion.Type] = append(byType[completion.Type], completion)
+	}
+
+	for _, completions := range byType {
+		sort.SliceStable(completions, func(i, j int) bool {
+			return completions[i].Count > completions[j].Count
+		})
+	}
+
+	// Round-robin across types so one type cannot crowd out the rest
+	results := make([]Completion, 0, limit)
+	for rank := 0; len(results) < limit; rank++ {
+		added := false
+		for _, completionType := range completionOrder {
+			completions := byType[completionType]
+			if rank < len(completions) && len(results) < limit {
+				results = append(results, completions[rank])
+				added = true
+			}
+		}
+		if !added {
+			break
+		}
+	}
+
+	return results
+}
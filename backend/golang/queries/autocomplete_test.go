@@ -0,0 +1,109 @@
+package queries
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// autocompleteIndexOf indexes completions the way Refresh does
+func autocompleteIndexOf(completions ...Completion) *AutocompleteIndex {
+	var entries []completionEntry
+	for _, completion := range completions {
+		entries = appendWordEntries(entries, completion)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].key < entries[j].key
+	})
+	return &AutocompleteIndex{entries: entries}
+}
+
+func TestAppendWordEntries(t *testing.T) {
+	entries := appendWordEntries(nil, Completion{Type: CompletionAuthor, Value: "Winston S. Churchill"})
+	var keys []string
+	for _, entry := range entries {
+		keys = append(keys, entry.key)
+	}
+	want := []string{"winston s. churchill", "s. churchill", "churchill"}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("keys = %q, want %q", keys, want)
+	}
+
+	if entries := appendWordEntries(nil, Completion{Type: CompletionTag}); len(entries) != 0 {
+		t.Errorf("an empty value is indexed: %+v", entries)
+	}
+}
+
+func TestComplete(t *testing.T) {
+	ai := autocompleteIndexOf(
+		Completion{Type: CompletionAuthor, Value: "Winston Churchill", Count: 30},
+		Completion{Type: CompletionAuthor, Value: "Lewis Carroll", Count: 12},
+		Completion{Type: CompletionTag, Value: "change", Count: 50},
+		Completion{Type: CompletionTag, Value: "character", Count: 80},
+		Completion{Type: CompletionTag, Value: "chance", Count: 5},
+		Completion{Type: CompletionCategory, Value: "Character Building", Count: 7},
+		Completion{Type: CompletionAuthor, Value: "Charles Dickens", Count: 20},
+	)
+
+	tests := []struct {
+		name   string
+		prefix string
+		limit  int
+		want   []Completion
+	}{
+		{
+			name:   "later word",
+			prefix: "chur",
+			limit:  5,
+			want:   []Completion{{Type: CompletionAuthor, Value: "Winston Churchill", Count: 30}},
+		},
+		{
+			name:   "case and space",
+			prefix: "  CARR ",
+			limit:  5,
+			want:   []Completion{{Type: CompletionAuthor, Value: "Lewis Carroll", Count: 12}},
+		},
+		{
+			// Types take turns in completionOrder, each by count
+			name:   "round robin",
+			prefix: "ch",
+			limit:  10,
+			want: []Completion{
+				{Type: CompletionTag, Value: "character", Count: 80},
+				{Type: CompletionAuthor, Value: "Winston Churchill", Count: 30},
+				{Type: CompletionCategory, Value: "Character Building", Count: 7},
+				{Type: CompletionTag, Value: "change", Count: 50},
+				{Type: CompletionAuthor, Value: "Charles Dickens", Count: 20},
+				{Type: CompletionTag, Value: "chance", Count: 5},
+			},
+		},
+		{
+			name:   "limit",
+			prefix: "ch",
+			limit:  2,
+			want: []Completion{
+				{Type: CompletionTag, Value: "character", Count: 80},
+				{Type: CompletionAuthor, Value: "Winston Churchill", Count: 30},
+			},
+		},
+		{name: "no match", prefix: "xyz", limit: 5, want: []Completion{}},
+		{name: "empty prefix", prefix: " ", limit: 5, want: []Completion{}},
+		{name: "zero limit", prefix: "ch", limit: 0, want: []Completion{}},
+	}
+
+	for _, tt := range tests {
+		if got := ai.Complete(tt.prefix, tt.limit); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Complete(%q, %d) = %+v, want %+v", tt.name, tt.prefix, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestCompleteDeduplicates(t *testing.T) {
+	// "Love and love" is indexed under both words, which share the prefix
+	ai := autocompleteIndexOf(Completion{Type: CompletionCategory, Value: "Love and love", Count: 3})
+
+	want := []Completion{{Type: CompletionCategory, Value: "Love and love", Count: 3}}
+	if got := ai.Complete("lo", 5); !reflect.DeepEqual(got, want) {
+		t.Errorf("Complete = %+v, want %+v", got, want)
+	}
+}
@@ -11,19 +11,59 @@ import (
 )
 
 type BrowseQueries struct {
-	db *pgxpool.Pool
+	db     *pgxpool.Pool
+	config SearchConfig
 }
 
-func NewBrowseQueries(db *pgxpool.Pool) *BrowseQueries {
-	return &BrowseQueries{db: db}
+func NewBrowseQueries(db *pgxpool.Pool, config SearchConfig) *BrowseQueries {
+	return &BrowseQueries{db: db, config: config}
 }
 
-func (bq *BrowseQueries) BuildStatement(params BrowseParams) (pgx.Rows, error) {
-	// Build WHERE clause
-	whereClause, args := bq.buildWhereClause(params)
+// Browse returns a page of quotes with its count and facets, in a single
+// statement when configured and the request is not paged by cursor or sampled
+func (bq *BrowseQueries) Browse(ctx context.Context, params BrowseParams) (BrowseResponse, error) {
+	if bq.config.SingleQuery && params.Cursor == "" && !bq.sampleable(params) {
+		return bq.browseSingle(ctx, params)
+	}
+
+	rows, err := bq.BuildStatement(ctx, params)
+	if err != nil {
+		return BrowseResponse{}, err
+	}
+	defer rows.Close()
+
+	return bq.BuildResponse(ctx, rows, params)
+}
+
+func (bq *BrowseQueries) BuildStatement(ctx context.Context, params BrowseParams) (pgx.Rows, error) {
+	args := &Args{}
+	filter := NewFilter(params)
+
+	// Unfiltered random first pages probe random ids instead of sorting the table
+	if bq.sampleable(params) {
+		return bq.db.Query(ctx, buildSampleStatement(args, params.Limit), args.Values()...)
+	}
 	
 	// Build ORDER BY clause
-	orderBy := bq.buildOrderBy(params.Sort, params.Order)
+	orderBy := bq.buildOrderBy(params.Sort, params.Order, params.Seed)
+
+	// Keyset paging: seek past the cursor and fetch one extra row to detect a next page
+	if params.Cursor != "" {
+		seek, err := bq.buildSeekCondition(params, args)
+		if err != nil {
+			return nil, err
+		}
+
+		sql := fmt.Sprintf(`
+			SELECT id, quote, author, category, tags, popularity, created_at
+			FROM quotes
+			%s
+			%s
+			LIMIT %s
+		`, filter.Where(args, seek), orderBy, args.Add(params.Limit+1))
+
+		return bq.db.Query(ctx, sql, args.Values()...)
+	}
 	
 	// Calculate OFFSET
 	offset := (params.Page - 1) * params.Limit
@@ -34,23 +74,30 @@ func (bq *BrowseQueries) BuildStatement(params BrowseParams) (pgx.Rows, error) {
 		FROM quotes
 		%s
 		%s
-		LIMIT $%d OFFSET $%d
-	`, whereClause, orderBy, len(args)+1, len(args)+2)
-	
-	// Add limit and offset to args
-	args = append(args, params.Limit, offset)
+		LIMIT %s OFFSET %s
+	`, filter.Where(args), orderBy, args.Add(params.Limit), args.Add(offset))
 	
-	return bq.db.Query(context.Background(), sql, args...)
+	return bq.db.Query(ctx, sql, args.Values()...)
 }
 
-func (bq *BrowseQueries) BuildResponse(rows pgx.Rows, params BrowseParams) (BrowseResponse, error) {
+func (bq *BrowseQueries) BuildResponse(ctx context.Context, rows pgx.Rows, params BrowseParams) (BrowseResponse, error) {
+	// Count and facets run while the page is read. A sample is unfiltered
+	// and has no pages to count through, so the table estimate stands in
+	// for a full count.
+	count := countQuotes
+	if bq.sampleable(params) {
+		count = estimateQuotes
+	}
+	aggregates := startAggregates(ctx, bq.db, NewFilter(params), params, bq.config.FacetTimeout, count)
+
 	var quotes []Quote
+	var createdAts []*time.Time
 	
 	// Parse quotes
 	for rows.Next() {
 		var q Quote
 		var tagsArray []string
-		var createdAt interface{}
+		var createdAt *time.Time
 		
 		err := rows.Scan(&q.ID, &q.Quote, &q.Author, &q.Category, &tagsArray, &q.Popularity, &createdAt)
 		if err != nil {
@@ -60,117 +107,114 @@ func (bq *BrowseQueries) BuildResponse(rows pgx.Rows, params BrowseParams) (Brow
 		q.Tags = tagsArray
 		// Handle created_at conversion if needed
 		if createdAt != nil {
-			if t, ok := createdAt.(time.Time); ok {
-				createdAtStr := t.Format(time.RFC3339)
-				q.CreatedAt = &createdAtStr
-			}
+			createdAtStr := createdAt.Format(time.RFC3339)
+			q.CreatedAt = &createdAtStr
 		}
 		quotes = append(quotes, q)
+		createdAts = append(createdAts, createdAt)
 	}
 
 	if err := rows.Err(); err != nil {
 		return BrowseResponse{}, err
 	}
 
-	// Get total count
-	totalCount, err := bq.getTotalCount(params)
+	// Get total count and facets
+	result, err := aggregates()
 	if err != nil {
 		return BrowseResponse{}, err
 	}
+	totalCount := result.totalCount
 
 	// Build pagination
 	pagination := bq.buildPagination(params.Page, params.Limit, totalCount)
+	if params.Cursor != "" {
+		pagination = bq.buildCursorPagination(params.Limit, totalCount, len(quotes) > params.Limit)
+		if len(quotes) > params.Limit {
+			quotes, createdAts = quotes[:params.Limit], createdAts[:params.Limit]
+		}
+	}
+	if pagination.HasNext && len(quotes) > 0 {
+		last := len(quotes) - 1
+		pagination.NextCursor = bq.nextCursor(params, quotes[last], createdAts[last])
+	}
 
 	// Build active filters
 	activeFilters := bq.buildActiveFilters(params)
 
 	response := BrowseResponse{
-		Quotes:        quotes,
-		Pagination:    pagination,
-		ActiveFilters: activeFilters,
-	}
-
-	// Add facets if requested
-	if params.IncludeFacets {
-		facets, err := bq.buildFacets(params)
-		if err != nil {
-			return BrowseResponse{}, err
-		}
-		response.Facets = facets
+		Quotes:         quotes,
+		Pagination:     pagination,
+		ActiveFilters:  activeFilters,
+		Facets:         result.facets,
+		FacetsTimedOut: result.facetsTimedOut,
 	}
 
 	return response, nil
 }
 
-func (bq *BrowseQueries) buildWhereClause(params BrowseParams) (string, []interface{}) {
-	var conditions []string
-	var args []interface{}
-	argIndex := 1
-
-	// Category filter
-	if len(params.Categories) > 0 {
-		placeholders := make([]string, len(params.Categories))
-		for i, category := range params.Categories {
-			placeholders[i] = fmt.Sprintf("$%d", argIndex)
-			args = append(args, category)
-			argIndex++
-		}
-		conditions = append(conditions, fmt.Sprintf("category = ANY(ARRAY[%s])", strings.Join(placeholders, ",")))
+// buildSeekCondition decodes params.Cursor into a predicate for the rows
+// after it in the requested sort. Random order can only be paged by cursor
+// with the seed the cursor was issued for.
+func (bq *BrowseQueries) buildSeekCondition(params BrowseParams, args *Args) (string, error) {
+	sort, order := bq.resolveSort(params.Sort, params.Order)
+	if sort == "random" && params.Seed == nil {
+		return "", ErrInvalidCursor
+	}
+	cursor, err := decodeCursor(params.Cursor, sort, order)
+	if err != nil {
+		return "", err
 	}
 
-	// Tags filter (AND logic - must have ALL specified tags)
-	if len(params.Tags) > 0 {
-		placeholders := make([]string, len(params.Tags))
-		for i, tag := range params.Tags {
-			placeholders[i] = fmt.Sprintf("$%d", argIndex)
-			args = append(args, tag)
-			argIndex++
+	if sort == "random" {
+		if cursor.Seed == nil || *cursor.Seed != *params.Seed {
+			return "", ErrInvalidCursor
 		}
-		conditions = append(conditions, fmt.Sprintf("tags @> ARRAY[%s]", strings.Join(placeholders, ",")))
+		return seededSeekCondition(args, *params.Seed, order == "desc", cursor.ID), nil
 	}
 
-	// Popularity range filter
-	if params.PopularityMin != nil {
-		conditions = append(conditions, fmt.Sprintf("popularity >= $%d", argIndex))
-		args = append(args, *params.PopularityMin)
-		argIndex++
-	}
-	if params.PopularityMax != nil {
-		conditions = append(conditions, fmt.Sprintf("popularity <= $%d", argIndex))
-		args = append(args, *params.PopularityMax)
-		argIndex++
+	var value interface{}
+	cast := "numeric"
+	if sort == "created_at" {
+		cast = "timestamp"
+		if cursor.Time != nil {
+			value = *cursor.Time
+		}
+	} else if cursor.Value != nil {
+		value = *cursor.Value
 	}
 
-	// Date range filter
-	if params.DateFrom != nil {
-		conditions = append(conditions, fmt.Sprintf("created_at >= $%d", argIndex))
-		args = append(args, *params.DateFrom)
-		argIndex++
-	}
-	if params.DateTo != nil {
-		conditions = append(conditions, fmt.Sprintf("created_at <= $%d", argIndex))
-		args = append(args, *params.DateTo)
-		argIndex++
-	}
+	return seekCondition(args, sort, cast, order == "desc", value, cursor.ID), nil
+}
 
-	if len(conditions) == 0 {
-		return "", args
+// nextCursor encodes the position after q, the last row of a page
+func (bq *BrowseQueries) nextCursor(params BrowseParams, q Quote, createdAt *time.Time) string {
+	sort, order := bq.resolveSort(params.Sort, params.Order)
+	if sort == "random" && params.Seed == nil {
+		return ""
 	}
 
-	return "WHERE " + strings.Join(conditions, " AND "), args
+	cursor := pageCursor{Sort: sort, Order: order, ID: q.ID}
+	if sort == "random" {
+		cursor.Seed = params.Seed
+	} else if sort == "created_at" {
+		cursor.Time = createdAt
+	} else {
+		cursor.Value = q.Popularity
+	}
+	return cursor.encode()
 }
 
-func (bq *BrowseQueries) buildOrderBy(sort, order string) string {
+// resolveSort validates the sort field and order, applying the defaults
+func (bq *BrowseQueries) resolveSort(sort, order string) (string, string) {
 	// Validate sort field
-	validSorts := map[string]string{
-		"popularity": "popularity",
-		"created_at": "created_at",
-		"random":     "RANDOM()",
+	validSorts := map[string]bool{
+		"popularity": true,
+		"created_at": true,
+		"random":     true,
 	}
 	
-	sortField, exists := validSorts[sort]
-	if !exists {
-		sortField = "popularity" // default
+	if !validSorts[sort] {
+		sort = "popularity" // default
 	}
 
 	// Validate order
@@ -178,26 +222,25 @@ func (bq *BrowseQueries) buildOrderBy(sort, order string) string {
 		order = "desc" // default
 	}
 
+	return sort, order
+}
+
+// buildOrderBy orders by the sort field with id as a tie-breaker, so pages are
+// stable and can be continued with a cursor. Random order is only stable with
+// a seed.
+func (bq *BrowseQueries) buildOrderBy(sort, order string, seed *int64) string {
+	sort, order = bq.resolveSort(sort, order)
+	direction := strings.ToUpper(order)
+
 	// Special case for random
 	if sort == "random" {
+		if seed != nil {
+			return seededOrderBy(*seed, direction)
+		}
 		return "ORDER BY RANDOM()"
 	}
 
-	return fmt.Sprintf("ORDER BY %s %s", sortField, strings.ToUpper(order))
-}
-
-func (bq *BrowseQueries) getTotalCount(params BrowseParams) (int, error) {
-	whereClause, args := bq.buildWhereClause(params)
-	
-	sql := fmt.Sprintf(`
-		SELECT COUNT(*) 
-		FROM quotes
-		%s
-	`, whereClause)
-	
-	var count int
-	err := bq.db.QueryRow(context.Background(), sql, args...).Scan(&count)
-	return count, err
+	return fmt.Sprintf("ORDER BY %s %s NULLS LAST, id %s", sort, direction, direction)
 }
 
 func (bq *BrowseQueries) buildPagination(page, limit, totalCount int) Pagination {
@@ -213,6 +256,17 @@ func (bq *BrowseQueries) buildPagination(page, limit, totalCount int) Pagination
 	}
 }
 
+// buildCursorPagination describes a keyset page, which has no page number
+func (bq *BrowseQueries) buildCursorPagination(limit, totalCount int, hasNext bool) Pagination {
+	return Pagination{
+		Limit:      limit,
+		TotalPages: (totalCount + limit - 1) / limit,
+		TotalCount: totalCount,
+		HasNext:    hasNext,
+		HasPrev:    true,
+	}
+}
+
 func (bq *BrowseQueries) buildActiveFilters(params BrowseParams) ActiveFilters {
 	return ActiveFilters{
 		Categories:    params.Categories,
@@ -224,144 +278,12 @@ func (bq *BrowseQueries) buildActiveFilters(params BrowseParams) ActiveFilters {
 	}
 }
 
-func (bq *BrowseQueries) buildFacets(params BrowseParams) (*Facets, error) {
-	facets := &Facets{}
-
-	// Get category facets
-	categoryFacets, err := bq.getCategoryFacets(params)
-	if err != nil {
-		return nil, err
-	}
-	facets.Categories = categoryFacets
-
-	// Get tag facets
-	tagFacets, err := bq.getTagFacets(params)
-	if err != nil {
-		return nil, err
-	}
-	facets.Tags = tagFacets
-
-	// Get popularity range
-	popularityRange, err := bq.getPopularityRange(params)
-	if err != nil {
-		return nil, err
-	}
-	facets.PopularityRange = popularityRange
-
-	return facets, nil
+// getCategoryFacets counts the categories of quotes matching params
+func (bq *BrowseQueries) getCategoryFacets(ctx context.Context, params BrowseParams) ([]FacetItem, error) {
+	return categoryFacets(ctx, bq.db, NewFilter(params), params.FacetLimit)
 }
 
-func (bq *BrowseQueries) getCategoryFacets(params BrowseParams) ([]FacetItem, error) {
-	// Build facet query without category filter to show all categories
-	facetParams := params
-	facetParams.Categories = nil
-	whereClause, args := bq.buildWhereClause(facetParams)
-	
-	// Handle the WHERE clause properly
-	if whereClause == "" {
-		whereClause = "WHERE category IS NOT NULL"
-	} else {
-		whereClause += " AND category IS NOT NULL"
-	}
-	
-	sql := fmt.Sprintf(`
-		SELECT category, COUNT(*) as count
-		FROM quotes
-		%s
-		GROUP BY category
-		ORDER BY count DESC
-		LIMIT $%d
-	`, whereClause, len(args)+1)
-	
-	args = append(args, params.FacetLimit)
-	
-	rows, err := bq.db.Query(context.Background(), sql, args...)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var facets []FacetItem
-	for rows.Next() {
-		var item FacetItem
-		err := rows.Scan(&item.Value, &item.Count)
-		if err != nil {
-			return nil, err
-		}
-		facets = append(facets, item)
-	}
-
-	return facets, rows.Err()
+// getTagFacets counts the tags of quotes matching params
+func (bq *BrowseQueries) getTagFacets(ctx context.Context, params BrowseParams) ([]FacetItem, error) {
+	return tagFacets(ctx, bq.db, NewFilter(params), params.FacetLimit)
 }
-
-func (bq *BrowseQueries) getTagFacets(params BrowseParams) ([]FacetItem, error) {
-	// Build facet query without tag filter to show all tags
-	facetParams := params
-	facetParams.Tags = nil
-	whereClause, args := bq.buildWhereClause(facetParams)
-	
-	sql := fmt.Sprintf(`
-		SELECT unnest(tags) as tag, COUNT(*) as count
-		FROM quotes
-		%s
-		GROUP BY tag
-		ORDER BY count DESC
-		LIMIT $%d
-	`, whereClause, len(args)+1)
-	
-	args = append(args, params.FacetLimit)
-	
-	rows, err := bq.db.Query(context.Background(), sql, args...)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var facets []FacetItem
-	for rows.Next() {
-		var item FacetItem
-		err := rows.Scan(&item.Value, &item.Count)
-		if err != nil {
-			return nil, err
-		}
-		facets = append(facets, item)
-	}
-
-	return facets, rows.Err()
-}
-
-func (bq *BrowseQueries) getPopularityRange(params BrowseParams) (*PopularityRange, error) {
-	// Build facet query without popularity filter
-	facetParams := params
-	facetParams.PopularityMin = nil
-	facetParams.PopularityMax = nil
-	whereClause, args := bq.buildWhereClause(facetParams)
-	
-	// Handle the WHERE clause properly
-	if whereClause == "" {
-		whereClause = "WHERE popularity IS NOT NULL"
-	} else {
-		whereClause += " AND popularity IS NOT NULL"
-	}
-	
-	sql := fmt.Sprintf(`
-		SELECT MIN(popularity), MAX(popularity)
-		FROM quotes
-		%s
-	`, whereClause)
-	
-	var min, max *float64
-	err := bq.db.QueryRow(context.Background(), sql, args...).Scan(&min, &max)
-	if err != nil {
-		return nil, err
-	}
-
-	if min == nil || max == nil {
-		return nil, nil
-	}
-
-	return &PopularityRange{
-		Min: *min,
-		Max: *max,
-	}, nil
-}
\ No newline at end of file
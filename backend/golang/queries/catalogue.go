@@ -0,0 +1,158 @@
+package queries
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrTagNotFound is returned when no quote carries a tag
+var ErrTagNotFound = errors.New("tag not found")
+
+// CatalogueParams represents parameters for the category and tag catalogues
+type CatalogueParams struct {
+	Page   int    `json:"page"`
+	Limit  int    `json:"limit"`
+	Prefix string `json:"prefix"`
+}
+
+// CatalogueResponse represents the response for category and tag catalogue APIs
+type CatalogueResponse struct {
+	Items      []FacetItem `json:"items"`
+	Pagination Pagination  `json:"pagination"`
+}
+
+// RelatedTagsResponse represents the response for tag co-occurrence API
+type RelatedTagsResponse struct {
+	Tag        string      `json:"tag"`
+	QuoteCount int         `json:"quote_count"`
+	Related    []FacetItem `json:"related"`
+}
+
+type CatalogueQueries struct {
+	db     *pgxpool.Pool
+	browse *BrowseQueries
+}
+
+func NewCatalogueQueries(db *pgxpool.Pool, browse *BrowseQueries) *CatalogueQueries {
+	return &CatalogueQueries{db: db, browse: browse}
+}
+
+// ListCategories returns every category with its quote count, most used first
+func (cq *CatalogueQueries) ListCategories(ctx context.Context, params CatalogueParams) (CatalogueResponse, error) {
+	return cq.listValues(ctx, "quotes", "category", params)
+}
+
+// ListTags returns every tag with its quote count, most used first
+func (cq *CatalogueQueries) ListTags(ctx context.Context, params CatalogueParams) (CatalogueResponse, error) {
+	return cq.listValues(ctx, "quotes, unnest(tags) AS tag", "tag", params)
+}
+
+// listValues counts quotes per distinct value of column over the from clause,
+// optionally restricted to values starting with params.Prefix
+func (cq *CatalogueQueries) listValues(ctx context.Context, from, column string, params CatalogueParams) (CatalogueResponse, error) {
+	whereClause := fmt.Sprintf("WHERE %s IS NOT NULL", column)
+	var args []interface{}
+	if params.Prefix != "" {
+		whereClause += fmt.Sprintf(" AND lower(%s) LIKE $1 ESCAPE '\\'", column)
+		args = append(args, likePrefix(strings.ToLower(params.Prefix)))
+	}
+
+	offset := (params.Page - 1) * params.Limit
+
+	sql := fmt.Sprintf(`
+		SELECT %s, COUNT(*) as count
+		FROM %s
+		%s
+		GROUP BY %s
+		ORDER BY count DESC, %s
+		LIMIT $%d OFFSET $%d
+	`, column, from, whereClause, column, column, len(args)+1, len(args)+2)
+
+	rows, err := cq.db.Query(ctx, sql, append(args, params.Limit, offset)...)
+	if err != nil {
+		return CatalogueResponse{}, err
+	}
+	defer rows.Close()
+
+	items := []FacetItem{}
+	for rows.Next() {
+		var item FacetItem
+		if err := rows.Scan(&item.Value, &item.Count); err != nil {
+			return CatalogueResponse{}, err
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return CatalogueResponse{}, err
+	}
+
+	// Get total count of distinct values
+	countSQL := fmt.Sprintf(`
+		SELECT COUNT(DISTINCT %s)
+		FROM %s
+		%s
+	`, column, from, whereClause)
+
+	var totalCount int
+	if err := cq.db.QueryRow(ctx, countSQL, args...).Scan(&totalCount); err != nil {
+		return CatalogueResponse{}, err
+	}
+
+	return CatalogueResponse{
+		Items:      items,
+		Pagination: cq.browse.buildPagination(params.Page, params.Limit, totalCount),
+	}, nil
+}
+
+// RelatedTags returns the tags that appear most often on quotes tagged with
+// tag. Returns ErrTagNotFound when no quote has the tag.
+func (cq *CatalogueQueries) RelatedTags(ctx context.Context, tag string, limit int) (RelatedTagsResponse, error) {
+	response := RelatedTagsResponse{Tag: tag, Related: []FacetItem{}}
+
+	countSQL := `
+		SELECT COUNT(*)
+		FROM quotes
+		WHERE tags @> ARRAY[$1]
+	`
+	if err := cq.db.QueryRow(ctx, countSQL, tag).Scan(&response.QuoteCount); err != nil {
+		return RelatedTagsResponse{}, err
+	}
+	if response.QuoteCount == 0 {
+		return RelatedTagsResponse{}, ErrTagNotFound
+	}
+
+	sql := `
+		SELECT other, COUNT(*) as count
+		FROM quotes, unnest(tags) AS other
+		WHERE tags @> ARRAY[$1] AND other <> $1
+		GROUP BY other
+		ORDER BY count DESC, other
+		LIMIT $2
+	`
+
+	rows, err := cq.db.Query(ctx, sql, tag, limit)
+	if err != nil {
+		return RelatedTagsResponse{}, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item FacetItem
+		if err := rows.Scan(&item.Value, &item.Count); err != nil {
+			return RelatedTagsResponse{}, err
+		}
+		response.Related = append(response.Related, item)
+	}
+
+	return response, rows.Err()
+}
+
+// likePrefix escapes LIKE wildcards in prefix and appends a trailing %
+func likePrefix(prefix string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return replacer.Replace(prefix) + "%"
+}
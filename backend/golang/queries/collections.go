@@ -0,0 +1,625 @@
+package queries
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrCollectionNotFound is returned when no collection has a slug, or when a
+// draft or personal collection is requested publicly
+var ErrCollectionNotFound = errors.New("collection not found")
+
+// ErrDuplicateCollection is returned when a write would violate uq_collections_owner_slug
+var ErrDuplicateCollection = errors.New("collection slug already exists")
+
+// MaxCollectionQuotes caps the size of a collection, which is hydrated whole
+const MaxCollectionQuotes = 500
+
+// Collection field limits, matching the column sizes of the collections table
+const (
+	maxCollectionSlug       = 100
+	maxCollectionTitle      = 200
+	maxCollectionCoverTheme = 50
+)
+
+// CollectionInput holds the editable fields of a collection. Nil fields are
+// left unchanged by updates; an empty description or cover theme clears it.
+type CollectionInput struct {
+	Slug        *string `json:"slug"`
+	Title       *string `json:"title"`
+	Description *string `json:"description"`
+	CoverTheme  *string `json:"cover_theme"`
+}
+
+// Normalize trims the fields and checks their lengths. Slugs and cover
+// themes are slugified, and on create a missing slug is derived from the
+// title. When partial is false the title is required.
+func (in CollectionInput) Normalize(partial bool) (CollectionInput, error) {
+	var out CollectionInput
+
+	if in.Title != nil || !partial {
+		if in.Title == nil {
+			return CollectionInput{}, &ValidationError{Field: "title", Message: "is required"}
+		}
+		title := strings.TrimSpace(*in.Title)
+		if title == "" {
+			return CollectionInput{}, &ValidationError{Field: "title", Message: "must not be empty"}
+		}
+		if utf8.RuneCountInString(title) > maxCollectionTitle {
+			return CollectionInput{}, &ValidationError{Field: "title", Message: fmt.Sprintf("must be at most %d characters", maxCollectionTitle)}
+		}
+		out.Title = &title
+	}
+
+	slugSource := in.Slug
+	if slugSource == nil && !partial {
+		slugSource = out.Title
+	}
+	if slugSource != nil {
+		slug := slugify(*slugSource)
+		if slug == "" {
+			return CollectionInput{}, &ValidationError{Field: "slug", Message: "must contain letters or digits"}
+		}
+		if len(slug) > maxCollectionSlug {
+			return CollectionInput{}, &ValidationError{Field: "slug", Message: fmt.Sprintf("must be at most %d characters", maxCollectionSlug)}
+		}
+		out.Slug = &slug
+	}
+
+	if in.Description != nil {
+		description := strings.TrimSpace(*in.Description)
+		out.Description = &description
+	}
+
+	if in.CoverTheme != nil {
+		theme := slugify(*in.CoverTheme)
+		if len(theme) > maxCollectionCoverTheme {
+			return CollectionInput{}, &ValidationError{Field: "cover_theme", Message: fmt.Sprintf("must be at most %d characters", maxCollectionCoverTheme)}
+		}
+		out.CoverTheme = &theme
+	}
+
+	return out, nil
+}
+
+// Collection is a curated, ordered list of quotes. Editorial collections are
+// public once published; personal collections belong to a user and stay private.
+type Collection struct {
+	ID          int     `json:"id"`
+	Slug        string  `json:"slug"`
+	Title       string  `json:"title"`
+	Description *string `json:"description,omitempty"`
+	CoverTheme  *string `json:"cover_theme,omitempty"`
+	Published   bool    `json:"published"`
+	PublishedAt *string `json:"published_at,omitempty"`
+	// QuoteIDs lists the quotes in collection order
+	QuoteIDs   []int `json:"quote_ids"`
+	QuoteCount int   `json:"quote_count"`
+	// Theme is the slug of the theme the collection was snapshotted from
+	Theme     *string `json:"theme,omitempty"`
+	CreatedAt *string `json:"created_at,omitempty"`
+	UpdatedAt *string `json:"updated_at,omitempty"`
+}
+
+// CollectionDetail is the public view of a collection with its quotes hydrated
+type CollectionDetail struct {
+	Collection
+	Quotes []Quote `json:"quotes"`
+}
+
+// CollectionListParams represents parameters for the collection directory
+type CollectionListParams struct {
+	Page  int `json:"page"`
+	Limit int `json:"limit"`
+}
+
+// CollectionListResponse represents the response for collection directory API
+type CollectionListResponse struct {
+	Collections []Collection `json:"collections"`
+	Pagination  Pagination   `json:"pagination"`
+}
+
+// collectionColumns selects a collection aliased c with its ordered quote IDs
+const collectionColumns = `c.id, c.slug, c.title, c.description, c.cover_theme, c.published_at, c.created_at, c.updated_at,
+	COALESCE((
+		SELECT array_agg(cq.quote_id ORDER BY cq.position, cq.quote_id)
+		FROM collection_quotes cq
+		WHERE cq.collection_id = c.id
+	), '{}') AS quote_ids,
+	(SELECT t.slug FROM themes t WHERE t.id = c.theme_id) AS theme`
+
+type CollectionQueries struct {
+	db     *pgxpool.Pool
+	quotes *QuoteQueries
+	browse *BrowseQueries
+	// owner scopes every query to one user's personal collections; nil is editorial
+	owner *int
+	// drafts lists and returns unpublished editorial collections too
+	drafts bool
+}
+
+func NewCollectionQueries(db *pgxpool.Pool, quotes *QuoteQueries, browse *BrowseQueries) *CollectionQueries {
+	return &CollectionQueries{
+		db:     db,
+		quotes: quotes,
+		browse: browse,
+	}
+}
+
+// ForOwner returns queries over the personal collections of a user
+func (cq *CollectionQueries) ForOwner(userID int) *CollectionQueries {
+	scoped := *cq
+	scoped.owner = &userID
+	return &scoped
+}
+
+// WithDrafts returns editorial queries that also list and return drafts, for
+// the admin views
+func (cq *CollectionQueries) WithDrafts() *CollectionQueries {
+	scoped := *cq
+	scoped.drafts = true
+	return &scoped
+}
+
+// listScope renders the condition and order of a collection listing: the
+// published editorial collections, most recently published first, or all of
+// the owner's collections, most recently changed first. With drafts, every
+// editorial collection is listed, drafts first.
+func (cq *CollectionQueries) listScope(args *Args) (string, string) {
+	if cq.owner != nil {
+		return "c.owner_id = " + args.Add(*cq.owner), "c.updated_at DESC, c.id DESC"
+	}
+	if cq.drafts {
+		return "c.owner_id IS NULL", "c.published_at DESC NULLS FIRST, c.updated_at DESC, c.id DESC"
+	}
+	return "c.owner_id IS NULL AND c.published_at IS NOT NULL", "c.published_at DESC, c.id DESC"
+}
+
+// ListCollections returns a page of published editorial collections, or of
+// the owner's collections when scoped with ForOwner
+func (cq *CollectionQueries) ListCollections(ctx context.Context, params CollectionListParams) (CollectionListResponse, error) {
+	args := &Args{}
+	scope, orderBy := cq.listScope(args)
+	sql := fmt.Sprintf(`
+		SELECT %s
+		FROM collections c
+		WHERE %s
+		ORDER BY %s
+		LIMIT %s OFFSET %s
+	`, collectionColumns, scope, orderBy, args.Add(params.Limit), args.Add((params.Page-1)*params.Limit))
+
+	rows, err := cq.db.Query(ctx, sql, args.Values()...)
+	if err != nil {
+		return CollectionListResponse{}, err
+	}
+	defer rows.Close()
+
+	collections := []Collection{}
+	for rows.Next() {
+		c, err := scanCollection(rows)
+		if err != nil {
+			return CollectionListResponse{}, err
+		}
+		collections = append(collections, c)
+	}
+	if err := rows.Err(); err != nil {
+		return CollectionListResponse{}, err
+	}
+
+	args = &Args{}
+	scope, _ = cq.listScope(args)
+	var totalCount int
+	err = cq.db.QueryRow(ctx, `SELECT COUNT(*) FROM collections c WHERE `+scope, args.Values()...).Scan(&totalCount)
+	if err != nil {
+		return CollectionListResponse{}, err
+	}
+
+	return CollectionListResponse{
+		Collections: collections,
+		Pagination:  cq.browse.buildPagination(params.Page, params.Limit, totalCount),
+	}, nil
+}
+
+// GetCollection returns a published editorial collection with its quotes in
+// order; drafts are reported as ErrCollectionNotFound unless the queries are
+// WithDrafts. Scoped with ForOwner it returns any of the owner's collections.
+func (cq *CollectionQueries) GetCollection(ctx context.Context, slug string) (CollectionDetail, error) {
+	sql := fmt.Sprintf(`
+		SELECT %s
+		FROM collections c
+		WHERE c.slug = $1 AND c.owner_id IS NOT DISTINCT FROM $2
+		  AND (c.published_at IS NOT NULL OR c.owner_id IS NOT NULL OR $3)
+	`, collectionColumns)
+
+	collection, err := scanCollection(cq.db.QueryRow(ctx, sql, slugify(slug), cq.owner, cq.drafts))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return CollectionDetail{}, ErrCollectionNotFound
+	}
+	if err != nil {
+		return CollectionDetail{}, err
+	}
+
+	sql = fmt.Sprintf(`
+		SELECT %s
+		FROM collection_quotes cq
+		JOIN quotes ON quotes.id = cq.quote_id
+		WHERE cq.collection_id = $1
+		ORDER BY cq.position, cq.quote_id
+	`, quoteColumns)
+
+	rows, err := cq.db.Query(ctx, sql, collection.ID)
+	if err != nil {
+		return CollectionDetail{}, err
+	}
+	defer rows.Close()
+
+	detail := CollectionDetail{Collection: collection, Quotes: []Quote{}}
+	for rows.Next() {
+		q, err := cq.quotes.scanQuote(rows)
+		if err != nil {
+			return CollectionDetail{}, err
+		}
+		detail.Quotes = append(detail.Quotes, q)
+	}
+	if err := rows.Err(); err != nil {
+		return CollectionDetail{}, err
+	}
+
+	// Quotes deleted since quote_ids was read are not listed
+	detail.QuoteIDs = make([]int, len(detail.Quotes))
+	for i, q := range detail.Quotes {
+		detail.QuoteIDs[i] = q.ID
+	}
+	detail.QuoteCount = len(detail.Quotes)
+
+	return detail, nil
+}
+
+// CreateCollection validates and inserts an empty, unpublished collection.
+// Returns ErrDuplicateCollection when the slug is taken.
+func (cq *CollectionQueries) CreateCollection(ctx context.Context, input CollectionInput) (Collection, error) {
+	input, err := input.Normalize(false)
+	if err != nil {
+		return Collection{}, err
+	}
+
+	sql := fmt.Sprintf(`
+		INSERT INTO collections AS c (slug, title, description, cover_theme, owner_id, created_at, updated_at)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), $5, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		RETURNING %s
+	`, collectionColumns)
+
+	c, err := scanCollection(cq.db.QueryRow(ctx, sql, *input.Slug, *input.Title, input.Description, input.CoverTheme, cq.owner))
+	return c, translateCollectionError(err)
+}
+
+// createSnapshot inserts an unpublished collection holding ids in order,
+// recording the theme it was taken from
+func (cq *CollectionQueries) createSnapshot(ctx context.Context, input CollectionInput, themeID int, ids []int) (Collection, error) {
+	input, err := input.Normalize(false)
+	if err != nil {
+		return Collection{}, err
+	}
+
+	tx, err := cq.db.Begin(ctx)
+	if err != nil {
+		return Collection{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	var id int
+	err = tx.QueryRow(ctx, `
+		INSERT INTO collections (slug, title, description, cover_theme, theme_id, owner_id, created_at, updated_at)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), $5, $6, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		RETURNING id
+	`, *input.Slug, *input.Title, input.Description, input.CoverTheme, themeID, cq.owner).Scan(&id)
+	if err != nil {
+		return Collection{}, translateCollectionError(err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO collection_quotes (collection_id, quote_id, position, added_at)
+		SELECT $1, ids.id, ids.ord, CURRENT_TIMESTAMP
+		FROM unnest($2::int[]) WITH ORDINALITY AS ids(id, ord)
+	`, id, ids)
+	if err != nil {
+		return Collection{}, err
+	}
+
+	sql := fmt.Sprintf(`SELECT %s FROM collections c WHERE c.id = $1`, collectionColumns)
+	collection, err := scanCollection(tx.QueryRow(ctx, sql, id))
+	if err != nil {
+		return Collection{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return Collection{}, err
+	}
+	return collection, nil
+}
+
+// UpdateCollection renames a collection or changes its slug, description or
+// cover theme. Returns ErrCollectionNotFound or ErrDuplicateCollection.
+func (cq *CollectionQueries) UpdateCollection(ctx context.Context, slug string, input CollectionInput) (Collection, error) {
+	input, err := input.Normalize(true)
+	if err != nil {
+		return Collection{}, err
+	}
+
+	args := &Args{}
+	var setClauses []string
+	if input.Slug != nil {
+		setClauses = append(setClauses, "slug = "+args.Add(*input.Slug))
+	}
+	if input.Title != nil {
+		setClauses = append(setClauses, "title = "+args.Add(*input.Title))
+	}
+	if input.Description != nil {
+		setClauses = append(setClauses, fmt.Sprintf("description = NULLIF(%s, '')", args.Add(*input.Description)))
+	}
+	if input.CoverTheme != nil {
+		setClauses = append(setClauses, fmt.Sprintf("cover_theme = NULLIF(%s, '')", args.Add(*input.CoverTheme)))
+	}
+	if len(setClauses) == 0 {
+		return Collection{}, &ValidationError{Field: "body", Message: "no fields to update"}
+	}
+
+	return cq.mutate(ctx, slug, func(tx pgx.Tx, id int) error {
+		sql := fmt.Sprintf(`UPDATE collections SET %s WHERE id = %s`, strings.Join(setClauses, ", "), args.Add(id))
+		_, err := tx.Exec(ctx, sql, args.Values()...)
+		return translateCollectionError(err)
+	})
+}
+
+// DeleteCollection removes a collection and its entries. Returns
+// ErrCollectionNotFound when it does not exist.
+func (cq *CollectionQueries) DeleteCollection(ctx context.Context, slug string) error {
+	tag, err := cq.db.Exec(ctx, `DELETE FROM collections WHERE slug = $1 AND owner_id IS NOT DISTINCT FROM $2`, slugify(slug), cq.owner)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrCollectionNotFound
+	}
+	return nil
+}
+
+// AddQuotes appends quotes to the end of a collection in the order given.
+// Quotes already in the collection keep their place. Unknown quote IDs and
+// growing past MaxCollectionQuotes are validation errors.
+func (cq *CollectionQueries) AddQuotes(ctx context.Context, slug string, ids []int) (Collection, error) {
+	ids, err := validateQuoteIDs(ids)
+	if err != nil {
+		return Collection{}, err
+	}
+
+	return cq.mutate(ctx, slug, func(tx pgx.Tx, id int) error {
+		var unknown []int
+		err := tx.QueryRow(ctx, `
+			SELECT COALESCE(array_agg(ids.id ORDER BY ids.ord), '{}')
+			FROM unnest($1::int[]) WITH ORDINALITY AS ids(id, ord)
+			WHERE NOT EXISTS (SELECT 1 FROM quotes WHERE quotes.id = ids.id)
+		`, ids).Scan(&unknown)
+		if err != nil {
+			return err
+		}
+		if len(unknown) > 0 {
+			return &ValidationError{Field: "quote_ids", Message: fmt.Sprintf("unknown quote ids %v", unknown)}
+		}
+
+		_, err = tx.Exec(ctx, `
+			INSERT INTO collection_quotes (collection_id, quote_id, position, added_at)
+			SELECT $1, ids.id,
+			       COALESCE((SELECT MAX(position) FROM collection_quotes WHERE collection_id = $1), 0) + ids.ord,
+			       CURRENT_TIMESTAMP
+			FROM unnest($2::int[]) WITH ORDINALITY AS ids(id, ord)
+			ON CONFLICT (collection_id, quote_id) DO NOTHING
+		`, id, ids)
+		if err != nil {
+			return err
+		}
+
+		var count int
+		err = tx.QueryRow(ctx, `SELECT COUNT(*) FROM collection_quotes WHERE collection_id = $1`, id).Scan(&count)
+		if err != nil {
+			return err
+		}
+		if count > MaxCollectionQuotes {
+			return &ValidationError{Field: "quote_ids", Message: fmt.Sprintf("a collection holds at most %d quotes", MaxCollectionQuotes)}
+		}
+		return nil
+	})
+}
+
+// RemoveQuote takes a quote out of a collection. Returns ErrQuoteNotFound
+// when the quote is not in it.
+func (cq *CollectionQueries) RemoveQuote(ctx context.Context, slug string, quoteID int) (Collection, error) {
+	return cq.mutate(ctx, slug, func(tx pgx.Tx, id int) error {
+		tag, err := tx.Exec(ctx, `DELETE FROM collection_quotes WHERE collection_id = $1 AND quote_id = $2`, id, quoteID)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrQuoteNotFound
+		}
+		return nil
+	})
+}
+
+// ReorderQuotes puts the quotes of a collection in the order given, which
+// must list every quote in the collection exactly once
+func (cq *CollectionQueries) ReorderQuotes(ctx context.Context, slug string, ids []int) (Collection, error) {
+	return cq.mutate(ctx, slug, func(tx pgx.Tx, id int) error {
+		rows, err := tx.Query(ctx, `SELECT quote_id FROM collection_quotes WHERE collection_id = $1`, id)
+		if err != nil {
+			return err
+		}
+		current, err := pgx.CollectRows(rows, pgx.RowTo[int])
+		if err != nil {
+			return err
+		}
+		if err := validateReorder(current, ids); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `
+			UPDATE collection_quotes cq
+			SET position = ids.ord
+			FROM unnest($2::int[]) WITH ORDINALITY AS ids(id, ord)
+			WHERE cq.collection_id = $1 AND cq.quote_id = ids.id
+		`, id, ids)
+		return err
+	})
+}
+
+// SetPublished publishes or unpublishes a collection. Publishing keeps the
+// original published_at when the collection is already public, and an empty
+// collection cannot be published. Personal collections stay private.
+func (cq *CollectionQueries) SetPublished(ctx context.Context, slug string, published bool) (Collection, error) {
+	if cq.owner != nil {
+		return Collection{}, &ValidationError{Field: "published", Message: "personal collections cannot be published"}
+	}
+
+	return cq.mutate(ctx, slug, func(tx pgx.Tx, id int) error {
+		if !published {
+			_, err := tx.Exec(ctx, `UPDATE collections SET published_at = NULL WHERE id = $1`, id)
+			return err
+		}
+
+		var empty bool
+		err := tx.QueryRow(ctx, `SELECT NOT EXISTS (SELECT 1 FROM collection_quotes WHERE collection_id = $1)`, id).Scan(&empty)
+		if err != nil {
+			return err
+		}
+		if empty {
+			return &ValidationError{Field: "quote_ids", Message: "an empty collection cannot be published"}
+		}
+
+		_, err = tx.Exec(ctx, `UPDATE collections SET published_at = COALESCE(published_at, CURRENT_TIMESTAMP) WHERE id = $1`, id)
+		return err
+	})
+}
+
+// mutate locks the collection with slug for a transaction, applies change
+// and bumps updated_at, returning the collection as changed. Returns
+// ErrCollectionNotFound when the slug does not exist.
+func (cq *CollectionQueries) mutate(ctx context.Context, slug string, change func(tx pgx.Tx, id int) error) (Collection, error) {
+	tx, err := cq.db.Begin(ctx)
+	if err != nil {
+		return Collection{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	var id int
+	err = tx.QueryRow(ctx, `
+		SELECT id FROM collections
+		WHERE slug = $1 AND owner_id IS NOT DISTINCT FROM $2
+		FOR UPDATE
+	`, slugify(slug), cq.owner).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Collection{}, ErrCollectionNotFound
+	}
+	if err != nil {
+		return Collection{}, err
+	}
+
+	if err := change(tx, id); err != nil {
+		return Collection{}, err
+	}
+
+	sql := fmt.Sprintf(`
+		UPDATE collections AS c
+		SET updated_at = CURRENT_TIMESTAMP
+		WHERE c.id = $1
+		RETURNING %s
+	`, collectionColumns)
+
+	collection, err := scanCollection(tx.QueryRow(ctx, sql, id))
+	if err != nil {
+		return Collection{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return Collection{}, err
+	}
+	return collection, nil
+}
+
+// validateQuoteIDs checks the IDs of an add request, dropping repeats
+func validateQuoteIDs(ids []int) ([]int, error) {
+	if len(ids) == 0 {
+		return nil, &ValidationError{Field: "quote_ids", Message: "is required"}
+	}
+	if len(ids) > MaxBatchIDs {
+		return nil, &ValidationError{Field: "quote_ids", Message: fmt.Sprintf("at most %d ids per request", MaxBatchIDs)}
+	}
+
+	unique := make([]int, 0, len(ids))
+	seen := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		if id <= 0 {
+			return nil, &ValidationError{Field: "quote_ids", Message: fmt.Sprintf("invalid quote id %d", id)}
+		}
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique, nil
+}
+
+// validateReorder checks that ids is a permutation of the current quote IDs
+func validateReorder(current, ids []int) error {
+	sortedCurrent := slices.Clone(current)
+	sortedIDs := slices.Clone(ids)
+	slices.Sort(sortedCurrent)
+	slices.Sort(sortedIDs)
+	if !slices.Equal(sortedCurrent, sortedIDs) {
+		return &ValidationError{Field: "quote_ids", Message: "must list every quote in the collection exactly once"}
+	}
+	return nil
+}
+
+// translateCollectionError maps the slug unique violation to ErrDuplicateCollection
+func translateCollectionError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "uq_collections_owner_slug" {
+		return ErrDuplicateCollection
+	}
+	return err
+}
+
+func scanCollection(row pgx.Row) (Collection, error) {
+	var c Collection
+	var publishedAt, createdAt, updatedAt *time.Time
+
+	err := row.Scan(&c.ID, &c.Slug, &c.Title, &c.Description, &c.CoverTheme, &publishedAt, &createdAt, &updatedAt, &c.QuoteIDs, &c.Theme)
+	if err != nil {
+		return Collection{}, err
+	}
+
+	c.QuoteCount = len(c.QuoteIDs)
+	c.Published = publishedAt != nil
+	for _, field := range []struct {
+		value *time.Time
+		dest  **string
+	}{
+		{publishedAt, &c.PublishedAt},
+		{createdAt, &c.CreatedAt},
+		{updatedAt, &c.UpdatedAt},
+	} {
+		if field.value != nil {
+			formatted := field.value.Format(time.RFC3339)
+			*field.dest = &formatted
+		}
+	}
+
+	return c, nil
+}
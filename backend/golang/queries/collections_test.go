@@ -0,0 +1,95 @@
+package queries
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCollectionInputNormalize(t *testing.T) {
+	title := "  Courage & Resilience "
+	theme := "Sunset Orange"
+	description := "  "
+	in, err := CollectionInput{Title: &title, CoverTheme: &theme, Description: &description}.Normalize(false)
+	if err != nil {
+		t.Fatalf("Normalize: %v", err)
+	}
+	if *in.Title != "Courage & Resilience" || *in.Slug != "courage-resilience" || *in.CoverTheme != "sunset-orange" || *in.Description != "" {
+		t.Errorf("Normalize = title %q, slug %q, theme %q, description %q", *in.Title, *in.Slug, *in.CoverTheme, *in.Description)
+	}
+
+	// Renames keep the slug unless one is given
+	in, err = CollectionInput{Title: &title}.Normalize(true)
+	if err != nil || in.Slug != nil {
+		t.Errorf("partial Normalize derived slug %v, err %v", in.Slug, err)
+	}
+
+	long := strings.Repeat("a", maxCollectionSlug+1)
+	punctuation := "!!!"
+	for name, input := range map[string]CollectionInput{
+		"title": {},
+		"slug":  {Title: &title, Slug: &punctuation},
+		"long":  {Title: &title, Slug: &long},
+	} {
+		var validationErr *ValidationError
+		if _, err := input.Normalize(false); !errors.As(err, &validationErr) {
+			t.Errorf("%s: Normalize error = %v, want a ValidationError", name, err)
+		}
+	}
+}
+
+func TestValidateQuoteIDs(t *testing.T) {
+	ids, err := validateQuoteIDs([]int{3, 1, 3, 2})
+	if err != nil || len(ids) != 3 || ids[0] != 3 || ids[2] != 2 {
+		t.Errorf("validateQuoteIDs = %v, %v; want [3 1 2]", ids, err)
+	}
+	for _, ids := range [][]int{nil, {1, 0}, make([]int, MaxBatchIDs+1)} {
+		if _, err := validateQuoteIDs(ids); err == nil {
+			t.Errorf("validateQuoteIDs(%d ids) accepted invalid ids", len(ids))
+		}
+	}
+}
+
+func TestValidateReorder(t *testing.T) {
+	current := []int{5, 9, 2}
+	if err := validateReorder(current, []int{2, 5, 9}); err != nil {
+		t.Errorf("validateReorder rejected a permutation: %v", err)
+	}
+	for _, ids := range [][]int{{2, 5}, {2, 5, 9, 9}, {2, 5, 7}, {2, 2, 9}} {
+		if err := validateReorder(current, ids); err == nil {
+			t.Errorf("validateReorder(%v) accepted ids that are not a permutation of %v", ids, current)
+		}
+	}
+}
+
+func TestCollectionListScope(t *testing.T) {
+	editorial := NewCollectionQueries(nil, nil, nil)
+	args := &Args{}
+	if scope, _ := editorial.listScope(args); scope != "c.owner_id IS NULL AND c.published_at IS NOT NULL" || len(args.Values()) != 0 {
+		t.Errorf("editorial scope = %q with args %v", scope, args.Values())
+	}
+
+	args = &Args{}
+	args.Add(1)
+	if scope, _ := editorial.ForOwner(7).listScope(args); scope != "c.owner_id = $2" || args.Values()[1] != 7 {
+		t.Errorf("owner scope = %q with args %v", scope, args.Values())
+	}
+	if editorial.owner != nil {
+		t.Errorf("ForOwner changed the editorial queries")
+	}
+
+	// Admins see every editorial collection, drafts included
+	args = &Args{}
+	if scope, orderBy := editorial.WithDrafts().listScope(args); scope != "c.owner_id IS NULL" || !strings.HasPrefix(orderBy, "c.published_at DESC NULLS FIRST") {
+		t.Errorf("drafts scope = %q ordered by %q", scope, orderBy)
+	}
+	if editorial.drafts {
+		t.Errorf("WithDrafts changed the editorial queries")
+	}
+
+	var validationErr *ValidationError
+	if _, err := editorial.ForOwner(7).SetPublished(context.Background(), "mine", true); !errors.As(err, &validationErr) {
+		t.Errorf("SetPublished on a personal collection error = %v, want a ValidationError", err)
+	}
+}
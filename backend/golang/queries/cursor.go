@@ -0,0 +1,64 @@
+package queries
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInvalidCursor is returned when a cursor cannot be decoded or was issued
+// for a different sort than the request
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// pageCursor is the position after the last row of a page: its sort key value
+// and id. It is sent to clients as an opaque base64 token.
+type pageCursor struct {
+	Sort  string     `json:"s"`
+	Order string     `json:"o"`
+	Value *float64   `json:"v,omitempty"`
+	Time  *time.Time `json:"t,omitempty"`
+	// Seed is set for seeded random order, whose position is recomputed from ID
+	Seed *int64 `json:"r,omitempty"`
+	ID   int    `json:"id"`
+}
+
+func (c pageCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor parses token and checks it belongs to the given sort and order
+func decodeCursor(token, sort, order string) (pageCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return pageCursor{}, ErrInvalidCursor
+	}
+	var c pageCursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return pageCursor{}, ErrInvalidCursor
+	}
+	if c.Sort != sort || c.Order != order {
+		return pageCursor{}, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// seekCondition renders the predicate for rows after the cursor in
+// "column DIR NULLS LAST, id DIR" order, adding its arguments to args. Rows
+// with a NULL sort key come last, so a cursor on a NULL value only moves along ids.
+func seekCondition(args *Args, column, cast string, desc bool, value interface{}, id int) string {
+	op := ">"
+	if desc {
+		op = "<"
+	}
+
+	if value == nil {
+		return fmt.Sprintf("(%s IS NULL AND id %s %s)", column, op, args.Add(id))
+	}
+
+	valueArg := args.Add(value)
+	return fmt.Sprintf("(%[1]s IS NULL OR %[1]s %[2]s %[3]s::%[4]s OR (%[1]s = %[3]s::%[4]s AND id %[2]s %[5]s))",
+		column, op, valueArg, cast, args.Add(id))
+}
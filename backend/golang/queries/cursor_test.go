@@ -0,0 +1,48 @@
+package queries
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	value := 0.87654321
+	token := pageCursor{Sort: "popularity", Order: "desc", Value: &value, ID: 42}.encode()
+
+	cursor, err := decodeCursor(token, "popularity", "desc")
+	if err != nil {
+		t.Fatalf("decodeCursor: %v", err)
+	}
+	if cursor.ID != 42 || cursor.Value == nil || *cursor.Value != value {
+		t.Errorf("decodeCursor = %+v, want id 42 and value %v", cursor, value)
+	}
+
+	for _, tc := range []struct{ token, sort, order string }{
+		{token, "created_at", "desc"},
+		{token, "popularity", "asc"},
+		{"not base64!", "popularity", "desc"},
+		{"e30", "score", "desc"},
+	} {
+		if _, err := decodeCursor(tc.token, tc.sort, tc.order); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("decodeCursor(%q, %s, %s) error = %v, want ErrInvalidCursor", tc.token, tc.sort, tc.order, err)
+		}
+	}
+}
+
+func TestSeekCondition(t *testing.T) {
+	args := &Args{}
+	args.Add("earlier")
+	args.Add("arguments")
+	sql := seekCondition(args, "popularity", "numeric", true, 0.5, 7)
+	want := "(popularity IS NULL OR popularity < $3::numeric OR (popularity = $3::numeric AND id < $4))"
+	if sql != want || len(args.Values()) != 4 {
+		t.Errorf("seekCondition desc =\n  %s %v\nwant\n  %s", sql, args.Values(), want)
+	}
+
+	args = &Args{}
+	sql = seekCondition(args, "created_at", "timestamp", false, nil, 7)
+	want = "(created_at IS NULL AND id > $1)"
+	if sql != want || len(args.Values()) != 1 {
+		t.Errorf("seekCondition on NULL =\n  %s %v\nwant\n  %s", sql, args.Values(), want)
+	}
+}
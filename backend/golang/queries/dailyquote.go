@@ -0,0 +1,283 @@
+package queries
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net/url"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrNoDailyQuote is returned when no quote matches the filters of a daily pick
+var ErrNoDailyQuote = errors.New("no quote matches the filters")
+
+// ErrDailyQuoteTooOld is returned for a date before the backfill window that
+// was never picked
+var ErrDailyQuoteTooOld = errors.New("date is too far in the past")
+
+// maxDailyFilterKey matches the column size of daily_quotes.filter_key
+const maxDailyFilterKey = 255
+
+// DailyQuoteFilter restricts the quotes a daily pick is drawn from. Each
+// filter set has its own sequence of picks.
+type DailyQuoteFilter struct {
+	Category string `json:"category,omitempty"`
+	Tag      string `json:"tag,omitempty"`
+}
+
+// key identifies the filter set in daily_quotes, empty when unfiltered
+func (f DailyQuoteFilter) key() string {
+	values := url.Values{}
+	if f.Category != "" {
+		values.Set("category", f.Category)
+	}
+	if f.Tag != "" {
+		values.Set("tag", f.Tag)
+	}
+	return values.Encode()
+}
+
+// validate rejects filter sets that cannot be stored or name a category or
+// tag no quote has, before a pick scans the candidates
+func (f DailyQuoteFilter) validate(ctx context.Context, db *pgxpool.Pool) error {
+	if len(f.key()) > maxDailyFilterKey {
+		return &ValidationError{Field: "filters", Message: fmt.Sprintf("must encode to at most %d characters", maxDailyFilterKey)}
+	}
+
+	var knownCategory, knownTag bool
+	err := db.QueryRow(ctx, `
+		SELECT $1 = '' OR EXISTS (SELECT 1 FROM quotes WHERE category = $1),
+		       $2 = '' OR EXISTS (SELECT 1 FROM quotes WHERE tags @> ARRAY[$2::text])
+	`, f.Category, f.Tag).Scan(&knownCategory, &knownTag)
+	if err != nil {
+		return err
+	}
+	if !knownCategory {
+		return &ValidationError{Field: "category", Message: "is not a known category"}
+	}
+	if !knownTag {
+		return &ValidationError{Field: "tag", Message: "is not a known tag"}
+	}
+	return nil
+}
+
+func (f DailyQuoteFilter) filter() Filter {
+	var filter Filter
+	if f.Category != "" {
+		filter.Categories = []string{f.Category}
+	}
+	if f.Tag != "" {
+		filter.Tags = []string{f.Tag}
+	}
+	return filter
+}
+
+// DailyQuote is the quote picked for a date and filter set
+type DailyQuote struct {
+	Date string `json:"date"`
+	// Timezone is the zone the date was resolved in
+	Timezone string `json:"timezone,omitempty"`
+	DailyQuoteFilter
+	Quote Quote `json:"quote"`
+}
+
+// DailyQuoteHistory represents the response for the quote of the day history API
+type DailyQuoteHistory struct {
+	Picks      []DailyQuote `json:"picks"`
+	Pagination Pagination   `json:"pagination"`
+}
+
+type DailyQuoteQueries struct {
+	db     *pgxpool.Pool
+	quotes *QuoteQueries
+	browse *BrowseQueries
+	// repeatWindow is how many days before and after a pick its quote is not picked again
+	repeatWindow int
+	// backfillDays is how many days before today a date without a pick is still picked
+	backfillDays int
+}
+
+func NewDailyQuoteQueries(db *pgxpool.Pool, quotes *QuoteQueries, browse *BrowseQueries, repeatWindow, backfillDays int) *DailyQuoteQueries {
+	return &DailyQuoteQueries{
+		db:           db,
+		quotes:       quotes,
+		browse:       browse,
+		repeatWindow: repeatWindow,
+		backfillDays: backfillDays,
+	}
+}
+
+// Pick returns the quote of the day for date, a calendar date at midnight
+// UTC no later than today. The first request for a date and filter set picks
+// the quote and stores it, so every later request sees the same one even as
+// quotes are added. Stored picks are returned for any date, but new ones are
+// only made within the backfill window and for known categories and tags,
+// so requests cannot grow daily_quotes without bound. Returns
+// ErrDailyQuoteTooOld before the window and ErrNoDailyQuote when nothing
+// matches the filters.
+func (dq *DailyQuoteQueries) Pick(ctx context.Context, date, today time.Time, filter DailyQuoteFilter) (DailyQuote, error) {
+	pick, err := dq.stored(ctx, date, filter)
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return pick, err
+	}
+
+	if date.Before(today.AddDate(0, 0, -dq.backfillDays)) {
+		return DailyQuote{}, ErrDailyQuoteTooOld
+	}
+	if err := filter.validate(ctx, dq.db); err != nil {
+		return DailyQuote{}, err
+	}
+	if err := dq.choose(ctx, date, filter); err != nil {
+		return DailyQuote{}, err
+	}
+
+	// A concurrent request may have stored the pick first; both chose the same quote
+	return dq.stored(ctx, date, filter)
+}
+
+// stored reads a pick saved by choose
+func (dq *DailyQuoteQueries) stored(ctx context.Context, date time.Time, filter DailyQuoteFilter) (DailyQuote, error) {
+	sql := fmt.Sprintf(`
+		SELECT %s
+		FROM daily_quotes d
+		JOIN quotes ON quotes.id = d.quote_id
+		WHERE d.filter_key = $1 AND d.pick_date = $2
+	`, quoteColumns)
+
+	q, err := dq.quotes.scanQuote(dq.db.QueryRow(ctx, sql, filter.key(), date))
+	if err != nil {
+		return DailyQuote{}, err
+	}
+	return DailyQuote{Date: date.Format(time.DateOnly), DailyQuoteFilter: filter, Quote: q}, nil
+}
+
+// choose picks the quote for a date and stores it. Quotes picked for the same
+// filters within the repeat window are skipped; when that leaves nothing, the
+// window is ignored. The pick is the candidate at an offset seeded by the
+// date and filters, so it does not depend on unseeded randomness and needs
+// no sort of the candidates beyond the primary key.
+func (dq *DailyQuoteQueries) choose(ctx context.Context, date time.Time, filter DailyQuoteFilter) error {
+	var quoteID int
+	found := false
+	for _, window := range []int{dq.repeatWindow, 0} {
+		sql, args := dailyPickSQL(filter, date, window)
+		err := dq.db.QueryRow(ctx, sql, args.Values()...).Scan(&quoteID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		found = true
+		break
+	}
+	if !found {
+		return ErrNoDailyQuote
+	}
+
+	_, err := dq.db.Exec(ctx, `
+		INSERT INTO daily_quotes (filter_key, pick_date, quote_id, picked_at)
+		VALUES ($1, $2, $3, CURRENT_TIMESTAMP)
+		ON CONFLICT (filter_key, pick_date) DO NOTHING
+	`, filter.key(), date, quoteID)
+	return err
+}
+
+// dailyPickSQL renders the statement choosing the candidate at the seeded
+// offset. Counting and choosing share one snapshot, so quotes added meanwhile
+// cannot move the offset past the end. A window of zero excludes no earlier
+// picks.
+func dailyPickSQL(filter DailyQuoteFilter, date time.Time, window int) (string, *Args) {
+	args := &Args{}
+	var extra []string
+	if window > 0 {
+		datePlaceholder := args.Add(date)
+		windowPlaceholder := args.Add(window)
+		extra = append(extra, fmt.Sprintf(`id NOT IN (
+			SELECT quote_id FROM daily_quotes
+			WHERE filter_key = %s
+			  AND pick_date <> %s
+			  AND pick_date BETWEEN %s::date - %s::int AND %s::date + %s::int
+		)`, args.Add(filter.key()), datePlaceholder, datePlaceholder, windowPlaceholder, datePlaceholder, windowPlaceholder))
+	}
+	whereClause := filter.filter().Where(args, extra...)
+
+	// The seed is kept non-negative as a bigint
+	seed := int64(dailySeed(date, filter) >> 1)
+	sql := fmt.Sprintf(`
+		WITH candidates AS (
+			SELECT id FROM quotes %s
+		)
+		SELECT id FROM candidates
+		ORDER BY id
+		OFFSET %s::bigint %% NULLIF((SELECT COUNT(*) FROM candidates), 0)
+		LIMIT 1
+	`, whereClause, args.Add(seed))
+	return sql, args
+}
+
+// dailySeed derives the pick seed from the date and filters, so a filter set
+// gets a different quote each day and different filter sets differ on a day
+func dailySeed(date time.Time, filter DailyQuoteFilter) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(date.Format(time.DateOnly)))
+	h.Write([]byte{0})
+	h.Write([]byte(filter.key()))
+	return h.Sum64()
+}
+
+// History returns a page of past picks for a filter set up to and including
+// until, most recent first
+func (dq *DailyQuoteQueries) History(ctx context.Context, filter DailyQuoteFilter, until time.Time, page, limit int) (DailyQuoteHistory, error) {
+	sql := fmt.Sprintf(`
+		SELECT d.pick_date, %s
+		FROM daily_quotes d
+		JOIN quotes ON quotes.id = d.quote_id
+		WHERE d.filter_key = $1 AND d.pick_date <= $2
+		ORDER BY d.pick_date DESC
+		LIMIT $3 OFFSET $4
+	`, quoteColumns)
+
+	rows, err := dq.db.Query(ctx, sql, filter.key(), until, limit, (page-1)*limit)
+	if err != nil {
+		return DailyQuoteHistory{}, err
+	}
+	defer rows.Close()
+
+	history := DailyQuoteHistory{Picks: []DailyQuote{}}
+	for rows.Next() {
+		var pickDate time.Time
+		q, err := dq.quotes.scanQuote(leadingColumnRow{rows, &pickDate})
+		if err != nil {
+			return DailyQuoteHistory{}, err
+		}
+		history.Picks = append(history.Picks, DailyQuote{Date: pickDate.Format(time.DateOnly), DailyQuoteFilter: filter, Quote: q})
+	}
+	if err := rows.Err(); err != nil {
+		return DailyQuoteHistory{}, err
+	}
+
+	var totalCount int
+	err = dq.db.QueryRow(ctx, `SELECT COUNT(*) FROM daily_quotes WHERE filter_key = $1 AND pick_date <= $2`, filter.key(), until).Scan(&totalCount)
+	if err != nil {
+		return DailyQuoteHistory{}, err
+	}
+	history.Pagination = dq.browse.buildPagination(page, limit, totalCount)
+
+	return history, nil
+}
+
+// leadingColumnRow scans the first column of row into dest and hands the
+// rest to the caller's Scan, so row scanners can be reused on wider rows
+type leadingColumnRow struct {
+	row  pgx.Row
+	dest interface{}
+}
+
+func (r leadingColumnRow) Scan(dest ...interface{}) error {
+	return r.row.Scan(append([]interface{}{r.dest}, dest...)...)
+}
@@ -0,0 +1,71 @@
+package queries
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDailyQuoteFilterKey(t *testing.T) {
+	tests := map[string]DailyQuoteFilter{
+		"":                       {},
+		"category=life":          {Category: "life"},
+		"category=life&tag=love": {Tag: "love", Category: "life"},
+		"tag=hope+%26+faith":     {Tag: "hope & faith"},
+	}
+	for want, filter := range tests {
+		if got := filter.key(); got != want {
+			t.Errorf("%+v key = %q, want %q", filter, got, want)
+		}
+	}
+}
+
+func TestDailyQuoteFilterValidateKeyLength(t *testing.T) {
+	// The length is checked before the catalogue is queried
+	filter := DailyQuoteFilter{Category: strings.Repeat("a", 100), Tag: strings.Repeat("b", 160)}
+	var validationErr *ValidationError
+	if err := filter.validate(context.Background(), nil); !errors.As(err, &validationErr) || validationErr.Field != "filters" {
+		t.Errorf("validate of a %d-character key error = %v, want a ValidationError on filters", len(filter.key()), err)
+	}
+}
+
+func TestDailySeed(t *testing.T) {
+	day := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+	life := DailyQuoteFilter{Category: "life"}
+
+	if dailySeed(day, life) != dailySeed(day, life) {
+		t.Errorf("dailySeed is not deterministic")
+	}
+	if dailySeed(day, life) == dailySeed(day.AddDate(0, 0, 1), life) {
+		t.Errorf("dailySeed is the same on consecutive days")
+	}
+	if dailySeed(day, life) == dailySeed(day, DailyQuoteFilter{}) {
+		t.Errorf("dailySeed ignores the filters")
+	}
+}
+
+func TestDailyPickSQL(t *testing.T) {
+	day := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+	filter := DailyQuoteFilter{Category: "life", Tag: "love"}
+
+	sql, args := dailyPickSQL(filter, day, 30)
+	for _, want := range []string{"NOT IN", "pick_date BETWEEN $1::date - $2::int", "category = ANY($4::text[])", "tags @> $5::text[]", "OFFSET $6::bigint"} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("pick SQL lacks %q:\n%s", want, sql)
+		}
+	}
+	values := args.Values()
+	if len(values) != 6 || values[2] != filter.key() {
+		t.Errorf("args = %v", values)
+	}
+	if seed, ok := values[5].(int64); !ok || seed < 0 {
+		t.Errorf("seed argument = %v, want a non-negative int64", values[5])
+	}
+
+	sql, args = dailyPickSQL(DailyQuoteFilter{}, day, 0)
+	if strings.Contains(sql, "daily_quotes") || strings.Contains(sql, "WHERE") || len(args.Values()) != 1 {
+		t.Errorf("pick SQL without a window or filters:\n%s", sql)
+	}
+}
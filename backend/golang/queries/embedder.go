@@ -0,0 +1,323 @@
+package queries
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EmbeddingDimensions is the size of the quotes.embedding vector column
+const EmbeddingDimensions = 256
+
+// Embedding providers
+const (
+	EmbeddingProviderHashing = "hashing"
+	EmbeddingProviderOpenAI  = "openai"
+)
+
+// ErrSemanticUnavailable is returned for semantic searches when no embedder
+// is configured
+var ErrSemanticUnavailable = errors.New("semantic search is not configured")
+
+// Embedder turns texts into EmbeddingDimensions-long vectors. Vectors from
+// different models are not comparable, so each embedder names its model.
+type Embedder interface {
+	Model() string
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// EmbedderConfig selects and configures an Embedder
+type EmbedderConfig struct {
+	// Provider is "hashing" (default, offline) or "openai" for any
+	// OpenAI-compatible /embeddings endpoint
+	Provider string
+	URL      string
+	APIKey   string
+	Model    string
+	Timeout  time.Duration
+}
+
+// NewEmbedder builds the embedder described by config
+func NewEmbedder(config EmbedderConfig) (Embedder, error) {
+	switch config.Provider {
+	case "", EmbeddingProviderHashing:
+		return NewHashingEmbedder(), nil
+	case EmbeddingProviderOpenAI:
+		if config.Model == "" {
+			return nil, fmt.Errorf("embedding provider %q requires a model", config.Provider)
+		}
+		url := config.URL
+		if url == "" {
+			url = "https://api.openai.com/v1/embeddings"
+		}
+		timeout := config.Timeout
+		if timeout <= 0 {
+			timeout = 10 * time.Second
+		}
+		return &HTTPEmbedder{
+			url:    url,
+			apiKey: config.APIKey,
+			model:  config.Model,
+			client: &http.Client{Timeout: timeout},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown embedding provider %q", config.Provider)
+	}
+}
+
+// HashingEmbedder is a deterministic offline embedder. Each word stem is
+// hashed into the vector, and words from a built-in theme lexicon also add
+// their themes, so "bouncing back after failure" lands near quotes about
+// falling down and getting up again without sharing a word with them.
+type HashingEmbedder struct {
+	themes map[string][]string // stem -> themes
+}
+
+func NewHashingEmbedder() *HashingEmbedder {
+	themes := make(map[string][]string)
+	for theme, words := range embeddingThemes {
+		for _, word := range words {
+			stem := stemWord(word)
+			if !slices.Contains(themes[stem], theme) {
+				themes[stem] = append(themes[stem], theme)
+			}
+		}
+	}
+	// Sum features in a fixed order so vectors are bit-for-bit reproducible
+	for _, list := range themes {
+		sort.Strings(list)
+	}
+	return &HashingEmbedder{themes: themes}
+}
+
+// Model names the hashing scheme; bump the version when it changes
+func (he *HashingEmbedder) Model() string {
+	return "hashing-v1"
+}
+
+func (he *HashingEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = he.embed(text)
+	}
+	return vectors, nil
+}
+
+const (
+	// stemWeight and themeWeight balance exact vocabulary against themes
+	stemWeight  = 1.0
+	themeWeight = 2.0
+)
+
+func (he *HashingEmbedder) embed(text string) []float32 {
+	vector := make([]float64, EmbeddingDimensions)
+	for _, token := range analyzeTokens(text) {
+		if embeddingStopwords[token] {
+			continue
+		}
+		stem := stemWord(token)
+		addHashed(vector, "w:"+stem, stemWeight)
+		for _, theme := range he.themes[stem] {
+			addHashed(vector, "t:"+theme, themeWeight)
+		}
+	}
+	return normalizeVector(vector)
+}
+
+// addHashed adds weight to the bucket feature hashes to, with a hashed sign
+// so collisions tend to cancel out
+func addHashed(vector []float64, feature string, weight float64) {
+	h := fnv.New64a()
+	h.Write([]byte(feature))
+	sum := h.Sum64()
+	if sum&(1<<63) != 0 {
+		weight = -weight
+	}
+	vector[sum%uint64(len(vector))] += weight
+}
+
+// normalizeVector scales vector to unit length; a zero vector stays zero
+func normalizeVector(vector []float64) []float32 {
+	var norm float64
+	for _, v := range vector {
+		norm += v * v
+	}
+	norm = math.Sqrt(norm)
+
+	result := make([]float32, len(vector))
+	if norm == 0 {
+		return result
+	}
+	for i, v := range vector {
+		result[i] = float32(v / norm)
+	}
+	return result
+}
+
+// stemWord strips common English suffixes so inflections share a feature
+func stemWord(word string) string {
+	switch {
+	case len(word) > 4 && strings.HasSuffix(word, "ies"):
+		word = word[:len(word)-3] + "y"
+	case len(word) > 5 && strings.HasSuffix(word, "ing"):
+		word = undouble(word[:len(word)-3])
+	case len(word) > 4 && strings.HasSuffix(word, "ed"):
+		word = undouble(word[:len(word)-2])
+	case len(word) > 4 && strings.HasSuffix(word, "ly"):
+		word = word[:len(word)-2]
+	case len(word) > 3 && strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss"):
+		word = word[:len(word)-1]
+	}
+	if len(word) > 4 && strings.HasSuffix(word, "e") {
+		word = word[:len(word)-1]
+	}
+	return word
+}
+
+// undouble turns "runn" back into "run"
+func undouble(word string) string {
+	n := len(word)
+	if n > 2 && word[n-1] == word[n-2] && !strings.ContainsRune("aeiouls", rune(word[n-1])) {
+		return word[:n-1]
+	}
+	return word
+}
+
+var embeddingStopwords = map[string]bool{
+	"a": true, "an": true, "the": true, "and": true, "or": true, "but": true,
+	"of": true, "to": true, "in": true, "on": true, "at": true, "for": true,
+	"by": true, "with": true, "from": true, "after": true, "before": true,
+	"is": true, "are": true, "was": true, "be": true, "been": true, "it": true,
+	"its": true, "that": true, "this": true, "as": true, "i": true, "you": true,
+	"we": true, "he": true, "she": true, "they": true, "my": true, "your": true,
+	"our": true, "not": true, "no": true, "do": true, "does": true, "so": true,
+}
+
+// embeddingThemes groups words by the theme they evoke in quotes
+var embeddingThemes = map[string][]string{
+	"resilience": {"bounce", "bouncing", "recover", "recovery", "resilience", "resilient", "rise", "rising",
+		"rose", "persevere", "perseverance", "persistence", "persist", "endure", "endurance", "overcome",
+		"comeback", "again", "tenacity", "grit", "continue"},
+	"adversity": {"failure", "fail", "failed", "fall", "fell", "fallen", "defeat", "setback", "mistake",
+		"loss", "lose", "adversity", "hardship", "struggle", "obstacle", "stumble", "error", "difficulty"},
+	"courage": {"courage", "brave", "bravery", "fear", "fearless", "bold", "daring", "valor", "afraid", "dare"},
+	"love":    {"love", "heart", "romance", "beloved", "affection", "adore", "passion", "darling", "kiss"},
+	"happiness": {"happy", "happiness", "joy", "joyful", "cheerful", "smile", "delight", "content",
+		"contentment", "glad", "laugh"},
+	"sorrow": {"sad", "sadness", "sorrow", "grief", "tears", "cry", "mourning", "pain", "heartbreak",
+		"melancholy", "despair", "lonely", "loneliness"},
+	"death":      {"death", "die", "dying", "dead", "mortality", "grave", "funeral", "mortal"},
+	"time":       {"time", "moment", "present", "future", "past", "yesterday", "tomorrow", "today", "hour", "age"},
+	"wisdom":     {"wisdom", "wise", "knowledge", "learn", "learning", "understand", "understanding", "truth", "insight", "teach", "education", "study", "mind"},
+	"friendship": {"friend", "friendship", "companion", "together", "loyalty", "trust"},
+	"success":    {"success", "successful", "achieve", "achievement", "goal", "win", "victory", "accomplish", "dream", "ambition"},
+	"change":     {"change", "transform", "grow", "growth", "evolve", "new", "beginning", "start"},
+	"hope":       {"hope", "hopeful", "faith", "believe", "optimism", "optimist", "light"},
+	"peace":      {"peace", "calm", "quiet", "serenity", "stillness", "rest", "tranquil", "silence"},
+	"work":       {"work", "effort", "labor", "diligence", "hard", "discipline", "practice"},
+	"freedom":    {"freedom", "free", "liberty", "independence"},
+	"nature":     {"nature", "tree", "sea", "ocean", "mountain", "sky", "river", "flower", "earth", "forest"},
+	"wealth":     {"money", "wealth", "rich", "poor", "poverty", "gold", "fortune"},
+	"life":       {"life", "live", "living", "alive", "existence"},
+}
+
+// HTTPEmbedder calls an OpenAI-compatible /embeddings endpoint, asking for
+// EmbeddingDimensions dimensions
+type HTTPEmbedder struct {
+	url    string
+	apiKey string
+	model  string
+	client *http.Client
+}
+
+func (he *HTTPEmbedder) Model() string {
+	return he.model
+}
+
+func (he *HTTPEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"model":      he.model,
+		"input":      texts,
+		"dimensions": EmbeddingDimensions,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, he.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if he.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+he.apiKey)
+	}
+
+	resp, err := he.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("embedding request failed: %s", resp.Status)
+	}
+
+	var result struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, item := range result.Data {
+		if item.Index < 0 || item.Index >= len(texts) || len(item.Embedding) != EmbeddingDimensions {
+			return nil, fmt.Errorf("embedding response has an invalid item at index %d", item.Index)
+		}
+		vectors[item.Index] = item.Embedding
+	}
+	for i, vector := range vectors {
+		if vector == nil {
+			return nil, fmt.Errorf("embedding response is missing index %d", i)
+		}
+	}
+	return vectors, nil
+}
+
+// isZeroVector reports whether vector carries no direction, which cosine
+// distance cannot compare
+func isZeroVector(vector []float32) bool {
+	for _, v := range vector {
+		if v != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// vectorLiteral renders vector in pgvector's text format for a ::vector cast
+func vectorLiteral(vector []float32) string {
+	var b strings.Builder
+	b.WriteByte('[')
+	for i, v := range vector {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.FormatFloat(float64(v), 'g', -1, 32))
+	}
+	b.WriteByte(']')
+	return b.String()
+}
@@ -0,0 +1,150 @@
+package queries
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func cosine(a, b []float32) float64 {
+	var dot float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+	}
+	return dot
+}
+
+func TestHashingEmbedder(t *testing.T) {
+	embedder := NewHashingEmbedder()
+	texts := []string{
+		"bouncing back after failure",
+		"Our greatest glory is not in never falling, but in rising every time we fall.",
+		"The best cooking recipes use fresh basil and tomatoes.",
+		"the of and",
+	}
+	vectors, err := embedder.Embed(context.Background(), texts)
+	if err != nil {
+		t.Fatalf("Embed: %v", err)
+	}
+
+	again, _ := embedder.Embed(context.Background(), texts[:1])
+	if cosine(vectors[0], again[0]) < 0.999999 || len(vectors[0]) != EmbeddingDimensions {
+		t.Errorf("Embed is not deterministic or has %d dimensions", len(vectors[0]))
+	}
+
+	related, unrelated := cosine(vectors[0], vectors[1]), cosine(vectors[0], vectors[2])
+	if related <= 0.3 || related <= unrelated {
+		t.Errorf("similarity to a quote about rising after falling = %.3f, to an unrelated one = %.3f", related, unrelated)
+	}
+
+	if !isZeroVector(vectors[3]) {
+		t.Errorf("stopwords embed to a non-zero vector")
+	}
+}
+
+func TestStemWord(t *testing.T) {
+	for word, want := range map[string]string{
+		"bouncing": "bounc",
+		"bounce":   "bounc",
+		"failed":   "fail",
+		"running":  "run",
+		"stories":  "story",
+		"falls":    "fall",
+		"glass":    "glass",
+	} {
+		if got := stemWord(word); got != want {
+			t.Errorf("stemWord(%q) = %q, want %q", word, got, want)
+		}
+	}
+}
+
+func TestHTTPEmbedder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var body struct {
+			Model      string   `json:"model"`
+			Input      []string `json:"input"`
+			Dimensions int      `json:"dimensions"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.Model != "test-model" || body.Dimensions != EmbeddingDimensions {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		// Answer out of order, as the index says where each vector belongs
+		type item struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		}
+		var data []item
+		for i := len(body.Input) - 1; i >= 0; i-- {
+			vector := make([]float32, EmbeddingDimensions)
+			vector[i] = 1
+			data = append(data, item{Index: i, Embedding: vector})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	}))
+	defer server.Close()
+
+	embedder, err := NewEmbedder(EmbedderConfig{Provider: EmbeddingProviderOpenAI, URL: server.URL, APIKey: "secret", Model: "test-model"})
+	if err != nil {
+		t.Fatalf("NewEmbedder: %v", err)
+	}
+	vectors, err := embedder.Embed(context.Background(), []string{"a", "b"})
+	if err != nil {
+		t.Fatalf("Embed: %v", err)
+	}
+	if vectors[0][0] != 1 || vectors[1][1] != 1 || embedder.Model() != "test-model" {
+		t.Errorf("Embed returned vectors out of order")
+	}
+
+	if _, err := NewEmbedder(EmbedderConfig{Provider: "word2vec"}); err == nil {
+		t.Errorf("NewEmbedder accepted an unknown provider")
+	}
+}
+
+func TestVectorSQL(t *testing.T) {
+	query, _ := ParseQuery("bouncing back after failure")
+	sq := NewSearchQueries(nil, DefaultSearchConfig(), nil, NewHashingEmbedder())
+	vector, err := sq.embedQuery(context.Background(), query)
+	if err != nil {
+		t.Fatalf("embedQuery: %v", err)
+	}
+	params := BrowseParams{Page: 2, Limit: 10, Mode: ModeHybrid, Tags: []string{"life"}}
+
+	sql, args := sq.vectorSQL(query, params, vector)
+	for _, want := range []string{"quotes_search_idx", "embedding <=>", "tags @>", "SUM(1.0 / ("} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("hybrid SQL lacks %q:\n%s", want, sql)
+		}
+	}
+	if literal, ok := args.Values()[1].(string); !ok || !strings.HasPrefix(literal, "[") || strings.Count(literal, ",") != EmbeddingDimensions-1 {
+		t.Errorf("vector argument = %v, want a pgvector literal", args.Values()[1])
+	}
+
+	params.Mode = ModeSemantic
+	sql, _ = sq.vectorSQL(query, params, make([]float32, EmbeddingDimensions))
+	if strings.Contains(sql, "embedding <=>") || strings.Contains(sql, "quotes_search_idx") {
+		t.Errorf("semantic SQL for a zero vector should rank nothing:\n%s", sql)
+	}
+
+	// Scopes and exclusions restrict the semantic candidates too
+	scoped, _ := ParseQuery("courage -war author:churchill")
+	sql, _ = sq.vectorSQL(scoped, BrowseParams{Page: 1, Limit: 10, Mode: ModeSemantic}, vector)
+	for _, want := range []string{"quotes_search_idx", "paradedb.match('author'", "must_not =>"} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("scoped semantic SQL lacks %q:\n%s", want, sql)
+		}
+	}
+
+	if _, err := NewSearchQueries(nil, DefaultSearchConfig(), nil, nil).embedQuery(context.Background(), query); err != ErrSemanticUnavailable {
+		t.Errorf("embedQuery without an embedder error = %v, want ErrSemanticUnavailable", err)
+	}
+}
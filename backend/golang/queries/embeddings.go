@@ -0,0 +1,105 @@
+package queries
+
+import (
+	"context"
+	"log"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// embeddingBatchSize is how many quotes are embedded per round trip
+const embeddingBatchSize = 256
+
+// EmbeddingQueries keeps quotes.embedding filled for the configured embedder
+type EmbeddingQueries struct {
+	db       *pgxpool.Pool
+	embedder Embedder
+}
+
+func NewEmbeddingQueries(db *pgxpool.Pool, embedder Embedder) *EmbeddingQueries {
+	return &EmbeddingQueries{db: db, embedder: embedder}
+}
+
+// Refresh embeds every quote whose embedding is missing or was produced by
+// another model, such as new quotes, edited quotes and everything after a
+// model change. It has the signature RefreshPeriodically expects.
+func (eq *EmbeddingQueries) Refresh(ctx context.Context) error {
+	model := eq.embedder.Model()
+	embedded := 0
+	afterID := 0
+	for {
+		ids, texts, err := eq.pending(ctx, model, afterID)
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			break
+		}
+
+		vectors, err := eq.embedder.Embed(ctx, texts)
+		if err != nil {
+			return err
+		}
+		if err := eq.store(ctx, model, ids, vectors); err != nil {
+			return err
+		}
+
+		embedded += len(ids)
+		afterID = ids[len(ids)-1]
+	}
+
+	if embedded > 0 {
+		log.Printf("Embedded %d quotes with %s", embedded, model)
+	}
+	return nil
+}
+
+// pending returns the next batch of quotes after afterID needing an embedding
+func (eq *EmbeddingQueries) pending(ctx context.Context, model string, afterID int) ([]int, []string, error) {
+	rows, err := eq.db.Query(ctx, `
+		SELECT id, quote
+		FROM quotes
+		WHERE id > $1 AND embedding_model IS DISTINCT FROM $2
+		ORDER BY id
+		LIMIT $3
+	`, afterID, model, embeddingBatchSize)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	var texts []string
+	for rows.Next() {
+		var id int
+		var text string
+		if err := rows.Scan(&id, &text); err != nil {
+			return nil, nil, err
+		}
+		ids = append(ids, id)
+		texts = append(texts, text)
+	}
+	return ids, texts, rows.Err()
+}
+
+// store saves a batch of vectors. Zero vectors, from quotes with no
+// meaningful words, are stored as NULL but still marked with the model so
+// they are not retried.
+func (eq *EmbeddingQueries) store(ctx context.Context, model string, ids []int, vectors [][]float32) error {
+	literals := make([]*string, len(vectors))
+	for i, vector := range vectors {
+		if !isZeroVector(vector) {
+			literal := vectorLiteral(vector)
+			literals[i] = &literal
+		}
+	}
+
+	// updated_at is left alone: embeddings are derived data
+	_, err := eq.db.Exec(ctx, `
+		UPDATE quotes
+		SET embedding = v.embedding::vector, embedding_model = $3
+		FROM unnest($1::int[], $2::text[]) AS v(id, embedding)
+		WHERE quotes.id = v.id
+	`, ids, literals, model)
+	return err
+}
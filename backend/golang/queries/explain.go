@@ -0,0 +1,187 @@
+package queries
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Explained statement names
+const (
+	ExplainPage            = "page"
+	ExplainCount           = "count"
+	ExplainFacetCategories = "facet_categories"
+	ExplainFacetTags       = "facet_tags"
+	ExplainFacetPopularity = "facet_popularity"
+	ExplainSingle          = "single"
+)
+
+// ExplainedStatement is one statement a search runs, with its plan and timings
+type ExplainedStatement struct {
+	Name string        `json:"name"`
+	SQL  string        `json:"sql"`
+	Args []interface{} `json:"args"`
+	// Plan is the EXPLAIN (ANALYZE, FORMAT JSON) output
+	Plan json.RawMessage `json:"plan"`
+	// PlanningMS and ExecutionMS are reported by Postgres; DurationMS is the
+	// round trip measured by the server
+	PlanningMS  float64 `json:"planning_ms"`
+	ExecutionMS float64 `json:"execution_ms"`
+	DurationMS  float64 `json:"duration_ms"`
+}
+
+// ExplainResponse describes how a search would be executed
+type ExplainResponse struct {
+	Query *ParsedQuery `json:"query"`
+	// Mode is "single" when the page, count and facets share one statement,
+	// "semantic" or "hybrid" for vector searches and "multi" otherwise
+	Mode       string               `json:"mode"`
+	Sort       string               `json:"sort"`
+	Order      string               `json:"order"`
+	Statements []ExplainedStatement `json:"statements"`
+	TotalMS    float64              `json:"total_ms"`
+}
+
+// explainStatement is a rendered statement waiting to be explained
+type explainStatement struct {
+	name string
+	sql  string
+	args *Args
+}
+
+// statements renders the statements Search would run for the request
+func (sq *SearchQueries) statements(ctx context.Context, query *ParsedQuery, params BrowseParams) (string, []explainStatement, error) {
+	if isVectorMode(params.Mode) {
+		if params.Cursor != "" {
+			return "", nil, ErrInvalidCursor
+		}
+		vector, err := sq.embedQuery(ctx, query)
+		if err != nil {
+			return "", nil, err
+		}
+		sql, args := sq.vectorSQL(query, params, vector)
+		statements := []explainStatement{{ExplainPage, sql, args}}
+		if params.IncludeFacets {
+			statements = append(statements, facetStatements(sq.vectorFilter(query, params), params.FacetLimit)...)
+		}
+		return params.Mode, statements, nil
+	}
+
+	if sq.config.SingleQuery && params.Cursor == "" && sq.singleRankable(params) {
+		sql, args := sq.singleSQL(query, params)
+		return "single", []explainStatement{{ExplainSingle, sql, args}}, nil
+	}
+
+	sql, args, err := sq.pageSQL(query, params)
+	if err != nil {
+		return "", nil, err
+	}
+	statements := []explainStatement{{ExplainPage, sql, args}}
+
+	filter := sq.filter(query, params)
+	sql, args = countSQL(filter)
+	statements = append(statements, explainStatement{ExplainCount, sql, args})
+
+	if params.IncludeFacets {
+		statements = append(statements, facetStatements(filter, params.FacetLimit)...)
+	}
+
+	return "multi", statements, nil
+}
+
+// facetStatements renders the facet queries buildFacets runs
+func facetStatements(filter Filter, limit int) []explainStatement {
+	categoriesSQL, categoriesArgs := categoryFacetsSQL(filter, limit)
+	tagsSQL, tagsArgs := tagFacetsSQL(filter, limit)
+	popularitySQL, popularityArgs := popularityRangeSQL(filter)
+	return []explainStatement{
+		{ExplainFacetCategories, categoriesSQL, categoriesArgs},
+		{ExplainFacetTags, tagsSQL, tagsArgs},
+		{ExplainFacetPopularity, popularitySQL, popularityArgs},
+	}
+}
+
+// Explain runs EXPLAIN ANALYZE for every statement a search would run. The
+// statements execute one after another in a read-only transaction, so their
+// timings do not overlap as they would in a real search. The fuzzy retry of
+// Search is not explained; pass fuzzy to explain a fuzzy search. Vector
+// searches explain their page and facet queries.
+func (sq *SearchQueries) Explain(ctx context.Context, query *ParsedQuery, params BrowseParams) (ExplainResponse, error) {
+	mode, statements, err := sq.statements(ctx, query, params)
+	if err != nil {
+		return ExplainResponse{}, err
+	}
+
+	tx, err := sq.db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return ExplainResponse{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	if isVectorMode(mode) {
+		if err := sq.widenVectorSearch(ctx, tx); err != nil {
+			return ExplainResponse{}, err
+		}
+	}
+
+	ranking := resolveSearchRanking(params.Sort, params.Order)
+	response := ExplainResponse{
+		Query:      query,
+		Mode:       mode,
+		Sort:       ranking.sort,
+		Order:      ranking.order,
+		Statements: make([]ExplainedStatement, 0, len(statements)),
+	}
+
+	started := time.Now()
+	for _, statement := range statements {
+		explained, err := explainOne(ctx, tx, statement)
+		if err != nil {
+			return ExplainResponse{}, fmt.Errorf("explain %s: %w", statement.name, err)
+		}
+		response.Statements = append(response.Statements, explained)
+	}
+	response.TotalMS = milliseconds(time.Since(started))
+
+	return response, nil
+}
+
+// explainOne runs one statement under EXPLAIN (ANALYZE, FORMAT JSON)
+func explainOne(ctx context.Context, tx pgx.Tx, statement explainStatement) (ExplainedStatement, error) {
+	started := time.Now()
+	var plan string
+	err := tx.QueryRow(ctx, "EXPLAIN (ANALYZE, FORMAT JSON) "+statement.sql, statement.args.Values()...).Scan(&plan)
+	if err != nil {
+		return ExplainedStatement{}, err
+	}
+
+	explained := ExplainedStatement{
+		Name:       statement.name,
+		SQL:        statement.sql,
+		Args:       statement.args.Values(),
+		Plan:       json.RawMessage(plan),
+		DurationMS: milliseconds(time.Since(started)),
+	}
+	if explained.Args == nil {
+		explained.Args = []interface{}{}
+	}
+
+	// The JSON format wraps the plan in a one-element array with the timings
+	var summary []struct {
+		PlanningTime  float64 `json:"Planning Time"`
+		ExecutionTime float64 `json:"Execution Time"`
+	}
+	if err := json.Unmarshal(explained.Plan, &summary); err == nil && len(summary) > 0 {
+		explained.PlanningMS = summary[0].PlanningTime
+		explained.ExecutionMS = summary[0].ExecutionTime
+	}
+
+	return explained, nil
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
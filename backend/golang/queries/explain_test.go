@@ -0,0 +1,67 @@
+package queries
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExplainStatements(t *testing.T) {
+	query, err := ParseQuery("courage tag:life")
+	if err != nil {
+		t.Fatalf("ParseQuery: %v", err)
+	}
+	params := BrowseParams{Page: 1, Limit: 20, IncludeFacets: true, FacetLimit: 10, Categories: []string{"wisdom"}}
+
+	names := func(statements []explainStatement) []string {
+		var result []string
+		for _, statement := range statements {
+			result = append(result, statement.name)
+		}
+		return result
+	}
+
+	ctx := context.Background()
+	sq := NewSearchQueries(nil, DefaultSearchConfig(), nil, NewHashingEmbedder())
+	mode, statements, err := sq.statements(ctx, query, params)
+	if err != nil {
+		t.Fatalf("statements: %v", err)
+	}
+	want := []string{ExplainPage, ExplainCount, ExplainFacetCategories, ExplainFacetTags, ExplainFacetPopularity}
+	if mode != "multi" || !reflect.DeepEqual(names(statements), want) {
+		t.Errorf("statements = %s %v, want multi %v", mode, names(statements), want)
+	}
+	for _, statement := range statements {
+		if !strings.Contains(statement.sql, "quotes_search_idx") {
+			t.Errorf("%s statement does not apply the search:\n%s", statement.name, statement.sql)
+		}
+		if strings.Count(statement.sql, "$") == 0 || len(statement.args.Values()) == 0 {
+			t.Errorf("%s statement has no bound arguments", statement.name)
+		}
+	}
+
+	params.IncludeFacets = false
+	if _, statements, _ := sq.statements(ctx, query, params); !reflect.DeepEqual(names(statements), want[:2]) {
+		t.Errorf("statements without facets = %v, want %v", names(statements), want[:2])
+	}
+
+	config := DefaultSearchConfig()
+	config.SingleQuery = true
+	single := NewSearchQueries(nil, config, nil, nil)
+	if mode, statements, _ := single.statements(ctx, query, params); mode != "single" || !reflect.DeepEqual(names(statements), []string{ExplainSingle}) {
+		t.Errorf("single statements = %s %v, want single [single]", mode, names(statements))
+	}
+
+	params.Mode = ModeHybrid
+	if mode, statements, _ := sq.statements(ctx, query, params); mode != ModeHybrid || !reflect.DeepEqual(names(statements), []string{ExplainPage}) {
+		t.Errorf("hybrid statements = %s %v, want hybrid [page]", mode, names(statements))
+	}
+	params.Mode = ""
+
+	params.Cursor = "not a cursor"
+	if _, _, err := sq.statements(ctx, query, params); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("statements with a bad cursor error = %v, want ErrInvalidCursor", err)
+	}
+}
@@ -0,0 +1,109 @@
+package queries
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// exportFetchSize is how many rows each FETCH pulls from the export cursor
+const exportFetchSize = 500
+
+// Record converts a quote to the JSON format read by cmd/ingest
+func (q Quote) Record() IngestRecord {
+	tags := q.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return IngestRecord{
+		Quote:      q.Quote,
+		Author:     q.Author,
+		Tags:       tags,
+		Popularity: q.Popularity,
+		Category:   q.Category,
+	}
+}
+
+type ExportQueries struct {
+	db     *pgxpool.Pool
+	browse *BrowseQueries
+	search *SearchQueries
+}
+
+func NewExportQueries(db *pgxpool.Pool, browse *BrowseQueries, search *SearchQueries) *ExportQueries {
+	return &ExportQueries{db: db, browse: browse, search: search}
+}
+
+// Export streams every quote matching params, and query when it is not nil,
+// to emit. Rows are read through a server-side cursor in a read-only
+// transaction, so memory stays flat and the export sees one snapshot. Paging
+// parameters are ignored. Both browse and search exports keep the requested
+// sort.
+func (eq *ExportQueries) Export(ctx context.Context, query *ParsedQuery, params BrowseParams, emit func(Quote) error) error {
+	args := &Args{}
+	var sql string
+	if query == nil {
+		sql = fmt.Sprintf(`
+			SELECT id, quote, author, category, tags, popularity, created_at
+			FROM quotes
+			%s
+			%s
+		`, NewFilter(params).Where(args), eq.browse.buildOrderBy(params.Sort, params.Order, params.Seed))
+	} else {
+		ranking := resolveSearchRanking(params.Sort, params.Order)
+		ranked := buildRankedSearch(args, eq.search.filter(query, params).Where(args), eq.search.config.Ranking, ranking.sort == SortBlended)
+		sql = fmt.Sprintf(`
+			SELECT id, quote, author, category, tags, popularity, created_at
+			FROM (%s) ranked
+			%s
+		`, ranked, ranking.orderBy())
+	}
+
+	tx, err := eq.db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "DECLARE quotes_export NO SCROLL CURSOR FOR "+sql, args.Values()...); err != nil {
+		return err
+	}
+
+	fetchSQL := fmt.Sprintf("FETCH FORWARD %d FROM quotes_export", exportFetchSize)
+	for {
+		rows, err := tx.Query(ctx, fetchSQL)
+		if err != nil {
+			return err
+		}
+
+		fetched := 0
+		for rows.Next() {
+			var q Quote
+			var createdAt *time.Time
+			if err := rows.Scan(&q.ID, &q.Quote, &q.Author, &q.Category, &q.Tags, &q.Popularity, &createdAt); err != nil {
+				rows.Close()
+				return err
+			}
+			if createdAt != nil {
+				createdAtStr := createdAt.Format(time.RFC3339)
+				q.CreatedAt = &createdAtStr
+			}
+			if err := emit(q); err != nil {
+				rows.Close()
+				return err
+			}
+			fetched++
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		if fetched < exportFetchSize {
+			return nil
+		}
+	}
+}
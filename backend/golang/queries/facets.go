@@ -0,0 +1,224 @@
+package queries
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"golang.org/x/sync/errgroup"
+)
+
+// countQuotes returns how many quotes match filter
+func countQuotes(ctx context.Context, db *pgxpool.Pool, filter Filter) (int, error) {
+	sql, args := countSQL(filter)
+
+	var count int
+	err := db.QueryRow(ctx, sql, args.Values()...).Scan(&count)
+	return count, err
+}
+
+// estimateQuotes returns the planner's estimate of how many quotes there are,
+// read from the table statistics instead of counting every row. A table that
+// has not been analyzed yet has no estimate and is counted instead.
+func estimateQuotes(ctx context.Context, db *pgxpool.Pool, filter Filter) (int, error) {
+	var estimate float64
+	err := db.QueryRow(ctx, `SELECT reltuples FROM pg_class WHERE oid = 'quotes'::regclass`).Scan(&estimate)
+	if err != nil || estimate < 0 {
+		return countQuotes(ctx, db, filter)
+	}
+	return int(estimate), nil
+}
+
+// buildFacets computes the category, tag and popularity facets for filter
+// concurrently. Each facet ignores its own filter so unselected values are
+// still offered.
+func buildFacets(ctx context.Context, db *pgxpool.Pool, filter Filter, limit int) (*Facets, error) {
+	facets := &Facets{}
+	g, ctx := errgroup.WithContext(ctx)
+
+	// Get category facets
+	g.Go(func() error {
+		var err error
+		facets.Categories, err = categoryFacets(ctx, db, filter, limit)
+		return err
+	})
+
+	// Get tag facets
+	g.Go(func() error {
+		var err error
+		facets.Tags, err = tagFacets(ctx, db, filter, limit)
+		return err
+	})
+
+	// Get popularity range
+	g.Go(func() error {
+		var err error
+		facets.PopularityRange, err = popularityRange(ctx, db, filter)
+		return err
+	})
+
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return facets, nil
+}
+
+// buildFacetsWithin runs buildFacets under timeout. Facets are optional, so
+// when they run out of time they are dropped and timedOut is reported instead
+// of failing the request. A timeout of zero leaves only ctx's deadline.
+func buildFacetsWithin(ctx context.Context, db *pgxpool.Pool, filter Filter, limit int, timeout time.Duration) (facets *Facets, timedOut bool, err error) {
+	facetCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		facetCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	facets, err = buildFacets(facetCtx, db, filter, limit)
+	if err != nil && ctx.Err() == nil && errors.Is(facetCtx.Err(), context.DeadlineExceeded) {
+		return nil, true, nil
+	}
+	return facets, false, err
+}
+
+// pageAggregates holds the total count and facets that accompany a result page
+type pageAggregates struct {
+	totalCount     int
+	facets         *Facets
+	facetsTimedOut bool
+}
+
+// quoteCounter totals the quotes matching a filter
+type quoteCounter func(ctx context.Context, db *pgxpool.Pool, filter Filter) (int, error)
+
+// startAggregates totals the quotes matching filter with count and, when
+// requested, builds facets for them concurrently with the caller. The
+// returned function waits for both.
+func startAggregates(ctx context.Context, db *pgxpool.Pool, filter Filter, params BrowseParams, facetTimeout time.Duration, count quoteCounter) func() (pageAggregates, error) {
+	var result pageAggregates
+	g, ctx := errgroup.WithContext(ctx)
+
+	g.Go(func() error {
+		var err error
+		result.totalCount, err = count(ctx, db, filter)
+		return err
+	})
+
+	if params.IncludeFacets {
+		g.Go(func() error {
+			var err error
+			result.facets, result.facetsTimedOut, err = buildFacetsWithin(ctx, db, filter, params.FacetLimit, facetTimeout)
+			return err
+		})
+	}
+
+	return func() (pageAggregates, error) {
+		err := g.Wait()
+		return result, err
+	}
+}
+
+// countSQL counts the quotes matching filter
+func countSQL(filter Filter) (string, *Args) {
+	args := &Args{}
+	sql := fmt.Sprintf(`
+		SELECT COUNT(*)
+		FROM quotes
+		%s
+	`, filter.Where(args))
+	return sql, args
+}
+
+func categoryFacets(ctx context.Context, db *pgxpool.Pool, filter Filter, limit int) ([]FacetItem, error) {
+	sql, args := categoryFacetsSQL(filter, limit)
+	return queryFacetItems(ctx, db, sql, args)
+}
+
+// categoryFacetsSQL counts the most common categories, ignoring the category filter
+func categoryFacetsSQL(filter Filter, limit int) (string, *Args) {
+	args := &Args{}
+	whereClause := filter.Without(FacetCategory).Where(args, "category IS NOT NULL")
+
+	sql := fmt.Sprintf(`
+		SELECT category, COUNT(*) as count
+		FROM quotes
+		%s
+		GROUP BY category
+		ORDER BY count DESC
+		LIMIT %s
+	`, whereClause, args.Add(limit))
+	return sql, args
+}
+
+func tagFacets(ctx context.Context, db *pgxpool.Pool, filter Filter, limit int) ([]FacetItem, error) {
+	sql, args := tagFacetsSQL(filter, limit)
+	return queryFacetItems(ctx, db, sql, args)
+}
+
+// tagFacetsSQL counts the most common tags, ignoring the tag filter
+func tagFacetsSQL(filter Filter, limit int) (string, *Args) {
+	args := &Args{}
+	whereClause := filter.Without(FacetTags).Where(args)
+
+	sql := fmt.Sprintf(`
+		SELECT unnest(tags) as tag, COUNT(*) as count
+		FROM quotes
+		%s
+		GROUP BY tag
+		ORDER BY count DESC
+		LIMIT %s
+	`, whereClause, args.Add(limit))
+	return sql, args
+}
+
+func popularityRange(ctx context.Context, db *pgxpool.Pool, filter Filter) (*PopularityRange, error) {
+	sql, args := popularityRangeSQL(filter)
+
+	var min, max *float64
+	err := db.QueryRow(ctx, sql, args.Values()...).Scan(&min, &max)
+	if err != nil {
+		return nil, err
+	}
+
+	if min == nil || max == nil {
+		return nil, nil
+	}
+
+	return &PopularityRange{
+		Min: *min,
+		Max: *max,
+	}, nil
+}
+
+// popularityRangeSQL finds the popularity bounds, ignoring the popularity filter
+func popularityRangeSQL(filter Filter) (string, *Args) {
+	args := &Args{}
+	sql := fmt.Sprintf(`
+		SELECT MIN(popularity), MAX(popularity)
+		FROM quotes
+		%s
+	`, filter.Without(FacetPopularity).Where(args, "popularity IS NOT NULL"))
+	return sql, args
+}
+
+func queryFacetItems(ctx context.Context, db *pgxpool.Pool, sql string, args *Args) ([]FacetItem, error) {
+	rows, err := db.Query(ctx, sql, args.Values()...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var facets []FacetItem
+	for rows.Next() {
+		var item FacetItem
+		err := rows.Scan(&item.Value, &item.Count)
+		if err != nil {
+			return nil, err
+		}
+		facets = append(facets, item)
+	}
+
+	return facets, rows.Err()
+}
@@ -0,0 +1,84 @@
+package queries
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Favorites are listed through browse and search with BrowseParams.FavoritesOf,
+// so they take the same filters, sorts, cursors and facets.
+type FavoriteQueries struct {
+	db *pgxpool.Pool
+}
+
+func NewFavoriteQueries(db *pgxpool.Pool) *FavoriteQueries {
+	return &FavoriteQueries{db: db}
+}
+
+// Add saves a quote as a favorite of a user. Saving it again does nothing.
+// Returns ErrQuoteNotFound when the quote does not exist.
+func (fq *FavoriteQueries) Add(ctx context.Context, userID, quoteID int) error {
+	_, err := fq.db.Exec(ctx, `
+		INSERT INTO favorites (user_id, quote_id, created_at)
+		VALUES ($1, $2, CURRENT_TIMESTAMP)
+		ON CONFLICT (user_id, quote_id) DO NOTHING
+	`, userID, quoteID)
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		return ErrQuoteNotFound
+	}
+	return err
+}
+
+// Remove takes a quote out of a user's favorites. Returns ErrQuoteNotFound
+// when it was not a favorite.
+func (fq *FavoriteQueries) Remove(ctx context.Context, userID, quoteID int) error {
+	tag, err := fq.db.Exec(ctx, `DELETE FROM favorites WHERE user_id = $1 AND quote_id = $2`, userID, quoteID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrQuoteNotFound
+	}
+	return nil
+}
+
+// Mark sets IsFavorite on each quote for a user
+func (fq *FavoriteQueries) Mark(ctx context.Context, userID int, quotes []Quote) error {
+	if len(quotes) == 0 {
+		return nil
+	}
+
+	ids := make([]int, len(quotes))
+	for i, q := range quotes {
+		ids[i] = q.ID
+	}
+
+	rows, err := fq.db.Query(ctx, `SELECT quote_id FROM favorites WHERE user_id = $1 AND quote_id = ANY($2)`, userID, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	favorites := make(map[int]bool, len(quotes))
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return err
+		}
+		favorites[id] = true
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i := range quotes {
+		isFavorite := favorites[quotes[i].ID]
+		quotes[i].IsFavorite = &isFavorite
+	}
+	return nil
+}
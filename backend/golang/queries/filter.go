@@ -0,0 +1,168 @@
+package queries
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Args collects positional arguments while a statement is built, so each
+// part of the SQL can add placeholders without tracking indexes itself
+type Args struct {
+	values []interface{}
+}
+
+// Add appends value and returns its placeholder
+func (a *Args) Add(value interface{}) string {
+	a.values = append(a.values, value)
+	return fmt.Sprintf("$%d", len(a.values))
+}
+
+// Next returns the index the next placeholder will get
+func (a *Args) Next() int {
+	return len(a.values) + 1
+}
+
+// Values returns the collected arguments in placeholder order
+func (a *Args) Values() []interface{} {
+	return a.values
+}
+
+// Facets that can be computed without their own filter
+const (
+	FacetCategory   = "category"
+	FacetTags       = "tags"
+	FacetPopularity = "popularity"
+)
+
+// Filter is the set of restrictions a request applies to quotes. Browse,
+// search, export, counts and facets all render it through Conditions, so a
+// filter is added here once and applied identically everywhere.
+type Filter struct {
+	// Query, when set, restricts quotes to full-text matches through the BM25 index
+	Query        *ParsedQuery
+	QueryOptions QueryOptions
+
+	Categories    []string
+	Tags          []string
+	PopularityMin *float64
+	PopularityMax *float64
+	DateFrom      *string
+	DateTo        *string
+	AuthorSlug    string
+	FavoritesOf   int
+}
+
+// NewFilter returns the filters set in params, without a full-text query
+func NewFilter(params BrowseParams) Filter {
+	return Filter{
+		Categories:    params.Categories,
+		Tags:          params.Tags,
+		PopularityMin: params.PopularityMin,
+		PopularityMax: params.PopularityMax,
+		DateFrom:      params.DateFrom,
+		DateTo:        params.DateTo,
+		AuthorSlug:    params.AuthorSlug,
+		FavoritesOf:   params.FavoritesOf,
+	}
+}
+
+// Without drops the filter on facet, so the facet counts every value the
+// user could switch to rather than only the selected ones
+func (f Filter) Without(facet string) Filter {
+	switch facet {
+	case FacetCategory:
+		f.Categories = nil
+	case FacetTags:
+		f.Tags = nil
+	case FacetPopularity:
+		f.PopularityMin = nil
+		f.PopularityMax = nil
+	}
+	return f
+}
+
+// Only keeps just the filter on facet
+func (f Filter) Only(facet string) Filter {
+	only := Filter{}
+	switch facet {
+	case FacetCategory:
+		only.Categories = f.Categories
+	case FacetTags:
+		only.Tags = f.Tags
+	case FacetPopularity:
+		only.PopularityMin = f.PopularityMin
+		only.PopularityMax = f.PopularityMax
+	}
+	return only
+}
+
+// Conditions renders each active filter as a SQL condition, adding its
+// arguments to args
+func (f Filter) Conditions(args *Args) []string {
+	var conditions []string
+
+	// Full-text query
+	if f.Query != nil {
+		querySQL, queryArgs := f.Query.ToSQL(args.Next(), f.QueryOptions)
+		args.values = append(args.values, queryArgs...)
+		conditions = append(conditions, fmt.Sprintf("quotes @@@ paradedb.with_index('quotes_search_idx', %s)", querySQL))
+	}
+
+	// Category filter (OR logic - any of the specified categories)
+	if len(f.Categories) > 0 {
+		conditions = append(conditions, fmt.Sprintf("category = ANY(%s::text[])", args.Add(f.Categories)))
+	}
+
+	// Tags filter (AND logic - must have ALL specified tags)
+	if len(f.Tags) > 0 {
+		conditions = append(conditions, fmt.Sprintf("tags @> %s::text[]", args.Add(f.Tags)))
+	}
+
+	// Popularity range filter
+	if f.PopularityMin != nil {
+		conditions = append(conditions, fmt.Sprintf("popularity >= %s", args.Add(*f.PopularityMin)))
+	}
+	if f.PopularityMax != nil {
+		conditions = append(conditions, fmt.Sprintf("popularity <= %s", args.Add(*f.PopularityMax)))
+	}
+
+	// Date range filter
+	if f.DateFrom != nil {
+		conditions = append(conditions, fmt.Sprintf("created_at >= %s", args.Add(*f.DateFrom)))
+	}
+	if f.DateTo != nil {
+		conditions = append(conditions, fmt.Sprintf("created_at <= %s", args.Add(*f.DateTo)))
+	}
+
+	// Author filter (slug groups punctuation variants of a name)
+	if f.AuthorSlug != "" {
+		conditions = append(conditions, fmt.Sprintf("%s = %s", authorSlugSQL, args.Add(f.AuthorSlug)))
+	}
+
+	// A user's favorites
+	if f.FavoritesOf != 0 {
+		conditions = append(conditions, fmt.Sprintf("id IN (SELECT quote_id FROM favorites WHERE user_id = %s)", args.Add(f.FavoritesOf)))
+	}
+
+	return conditions
+}
+
+// Predicate renders the filter as a single boolean expression, TRUE when it
+// restricts nothing
+func (f Filter) Predicate(args *Args) string {
+	conditions := f.Conditions(args)
+	if len(conditions) == 0 {
+		return "TRUE"
+	}
+	return "(" + strings.Join(conditions, " AND ") + ")"
+}
+
+// Where renders the filter and any extra conditions as a WHERE clause, or an
+// empty string when there is nothing to restrict
+func (f Filter) Where(args *Args, extra ...string) string {
+	conditions := append(f.Conditions(args), extra...)
+	if len(conditions) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(conditions, " AND ")
+}
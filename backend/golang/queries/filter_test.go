@@ -0,0 +1,128 @@
+package queries
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFilterWhere(t *testing.T) {
+	min, max := 0.2, 0.9
+	from, to := "2024-01-01", "2024-12-31"
+	params := BrowseParams{
+		Categories:    []string{"life", "love"},
+		Tags:          []string{"hope"},
+		PopularityMin: &min,
+		PopularityMax: &max,
+		DateFrom:      &from,
+		DateTo:        &to,
+		AuthorSlug:    "dr-seuss",
+	}
+
+	tests := []struct {
+		name     string
+		filter   Filter
+		extra    []string
+		wantSQL  string
+		wantArgs []interface{}
+	}{
+		{
+			name:    "empty",
+			filter:  Filter{},
+			wantSQL: "",
+		},
+		{
+			name:    "extra only",
+			filter:  Filter{},
+			extra:   []string{"category IS NOT NULL"},
+			wantSQL: "WHERE category IS NOT NULL",
+		},
+		{
+			name:   "all filters",
+			filter: NewFilter(params),
+			extra:  []string{"popularity IS NOT NULL"},
+			wantSQL: "WHERE category = ANY($1::text[]) AND tags @> $2::text[] AND popularity >= $3 AND popularity <= $4" +
+				" AND created_at >= $5 AND created_at <= $6 AND " + authorSlugSQL + " = $7 AND popularity IS NOT NULL",
+			wantArgs: []interface{}{params.Categories, params.Tags, min, max, from, to, "dr-seuss"},
+		},
+		{
+			name:     "without category",
+			filter:   NewFilter(BrowseParams{Categories: []string{"life"}, Tags: []string{"hope"}}).Without(FacetCategory),
+			wantSQL:  "WHERE tags @> $1::text[]",
+			wantArgs: []interface{}{[]string{"hope"}},
+		},
+		{
+			name:     "without tags",
+			filter:   NewFilter(BrowseParams{Categories: []string{"life"}, Tags: []string{"hope"}}).Without(FacetTags),
+			wantSQL:  "WHERE category = ANY($1::text[])",
+			wantArgs: []interface{}{[]string{"life"}},
+		},
+		{
+			name:     "without popularity",
+			filter:   NewFilter(BrowseParams{PopularityMin: &min, PopularityMax: &max, DateFrom: &from}).Without(FacetPopularity),
+			wantSQL:  "WHERE created_at >= $1",
+			wantArgs: []interface{}{from},
+		},
+		{
+			name:     "favorites keep facet filters",
+			filter:   NewFilter(BrowseParams{Tags: []string{"hope"}, FavoritesOf: 7}).Without(FacetTags),
+			wantSQL:  "WHERE id IN (SELECT quote_id FROM favorites WHERE user_id = $1)",
+			wantArgs: []interface{}{7},
+		},
+	}
+
+	for _, tt := range tests {
+		args := &Args{}
+		sql := tt.filter.Where(args, tt.extra...)
+		if sql != tt.wantSQL {
+			t.Errorf("%s: sql =\n%s\nwant\n%s", tt.name, sql, tt.wantSQL)
+		}
+		if !reflect.DeepEqual(args.Values(), tt.wantArgs) {
+			t.Errorf("%s: args = %v, want %v", tt.name, args.Values(), tt.wantArgs)
+		}
+	}
+}
+
+func TestFilterWithQuery(t *testing.T) {
+	pq, err := ParseQuery("courage")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Earlier placeholders are kept and the query numbers on from them
+	args := &Args{}
+	args.Add(42)
+	filter := NewFilter(BrowseParams{Categories: []string{"life"}})
+	filter.Query = pq
+	filter.QueryOptions = QueryOptions{Boosts: SearchBoosts{Quote: 1}}
+
+	sql := filter.Where(args)
+	wantSQL := "WHERE quotes @@@ paradedb.with_index('quotes_search_idx', " +
+		"paradedb.boolean(should => ARRAY[paradedb.boolean(should => ARRAY[paradedb.boost(1, paradedb.match('quote', $2::text))])]))" +
+		" AND category = ANY($3::text[])"
+	if sql != wantSQL {
+		t.Errorf("sql =\n%s\nwant\n%s", sql, wantSQL)
+	}
+	wantArgs := []interface{}{42, "courage", []string{"life"}}
+	if !reflect.DeepEqual(args.Values(), wantArgs) {
+		t.Errorf("args = %v, want %v", args.Values(), wantArgs)
+	}
+}
+
+func TestFilterOnlyPredicate(t *testing.T) {
+	min := 0.5
+	filter := NewFilter(BrowseParams{Categories: []string{"life"}, PopularityMin: &min, AuthorSlug: "dr-seuss"})
+
+	args := &Args{}
+	got := []string{
+		filter.Only(FacetCategory).Predicate(args),
+		filter.Only(FacetTags).Predicate(args),
+		filter.Only(FacetPopularity).Predicate(args),
+	}
+	want := []string{"(category = ANY($1::text[]))", "TRUE", "(popularity >= $2)"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("predicates = %v, want %v", got, want)
+	}
+	if len(args.Values()) != 2 {
+		t.Errorf("got %d args, want 2", len(args.Values()))
+	}
+}
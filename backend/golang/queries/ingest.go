@@ -0,0 +1,196 @@
+package queries
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// IngestRecord is a quote in the JSON format read by data-ingestion/
+type IngestRecord struct {
+	Quote      string   `json:"Quote"`
+	Author     string   `json:"Author"`
+	Tags       []string `json:"Tags"`
+	Popularity *float64 `json:"Popularity"`
+	Category   *string  `json:"Category"`
+}
+
+// Input converts the record for validation with QuoteInput.Normalize
+func (r IngestRecord) Input() QuoteInput {
+	input := QuoteInput{
+		Quote:      &r.Quote,
+		Author:     &r.Author,
+		Popularity: r.Popularity,
+		Category:   r.Category,
+	}
+	if r.Tags != nil {
+		input.Tags = &r.Tags
+	}
+	return input
+}
+
+// IngestResult counts the outcome of merging a batch into quotes
+type IngestResult struct {
+	Inserted   int `json:"inserted"`
+	Updated    int `json:"updated"`
+	Unchanged  int `json:"unchanged"`
+	Duplicates int `json:"duplicates"`
+}
+
+// Add accumulates another batch result
+func (r *IngestResult) Add(other IngestResult) {
+	r.Inserted += other.Inserted
+	r.Updated += other.Updated
+	r.Unchanged += other.Unchanged
+	r.Duplicates += other.Duplicates
+}
+
+type IngestQueries struct {
+	db *pgxpool.Pool
+}
+
+func NewIngestQueries(db *pgxpool.Pool) *IngestQueries {
+	return &IngestQueries{db: db}
+}
+
+// UpsertBatch merges a batch of normalized quotes into quotes in its own
+// transaction
+func (iq *IngestQueries) UpsertBatch(ctx context.Context, batch []QuoteInput) (IngestResult, error) {
+	tx, err := iq.db.Begin(ctx)
+	if err != nil {
+		return IngestResult{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := mergeBatch(ctx, tx, batch)
+	if err != nil {
+		return IngestResult{}, err
+	}
+	return result, tx.Commit(ctx)
+}
+
+// IngestDryRun merges batches inside one transaction that is never committed.
+// Each batch runs in a savepoint, so a batch Postgres refuses is undone on its
+// own, and every batch is counted against the quotes earlier batches merged.
+type IngestDryRun struct {
+	tx pgx.Tx
+}
+
+// BeginDryRun starts a dry run; Rollback must be called to end it
+func (iq *IngestQueries) BeginDryRun(ctx context.Context) (*IngestDryRun, error) {
+	tx, err := iq.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &IngestDryRun{tx: tx}, nil
+}
+
+// UpsertBatch merges a batch as IngestQueries.UpsertBatch would, without
+// writing it
+func (d *IngestDryRun) UpsertBatch(ctx context.Context, batch []QuoteInput) (IngestResult, error) {
+	savepoint, err := d.tx.Begin(ctx)
+	if err != nil {
+		return IngestResult{}, err
+	}
+	defer savepoint.Rollback(ctx)
+
+	result, err := mergeBatch(ctx, savepoint, batch)
+	if err != nil {
+		return IngestResult{}, err
+	}
+	return result, savepoint.Commit(ctx)
+}
+
+// Rollback discards everything the dry run merged
+func (d *IngestDryRun) Rollback(ctx context.Context) error {
+	return d.tx.Rollback(ctx)
+}
+
+// mergeBatch copies normalized quotes into a temporary staging table and
+// merges them into quotes on (quote, author). When a batch repeats a
+// quote/author pair the last one wins.
+func mergeBatch(ctx context.Context, tx pgx.Tx, batch []QuoteInput) (IngestResult, error) {
+	_, err := tx.Exec(ctx, `
+		CREATE TEMP TABLE quotes_staging (
+			seq INTEGER NOT NULL,
+			quote TEXT NOT NULL,
+			author VARCHAR(255) NOT NULL,
+			tags TEXT[] NOT NULL,
+			popularity DECIMAL(10, 8),
+			category VARCHAR(100)
+		) ON COMMIT DROP
+	`)
+	if err != nil {
+		return IngestResult{}, err
+	}
+
+	_, err = tx.CopyFrom(ctx,
+		pgx.Identifier{"quotes_staging"},
+		[]string{"seq", "quote", "author", "tags", "popularity", "category"},
+		pgx.CopyFromSlice(len(batch), func(i int) ([]interface{}, error) {
+			in := batch[i]
+			tags := []string{}
+			if in.Tags != nil {
+				tags = *in.Tags
+			}
+			return []interface{}{i, *in.Quote, *in.Author, tags, in.Popularity, in.Category}, nil
+		}),
+	)
+	if err != nil {
+		return IngestResult{}, err
+	}
+
+	// Rows whose data is identical are not touched, so updated_at only moves on change
+	rows, err := tx.Query(ctx, `
+		INSERT INTO quotes (quote, author, tags, popularity, category, created_at, updated_at)
+		SELECT DISTINCT ON (quote, author)
+		       quote, author, tags, popularity, NULLIF(category, ''), CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
+		FROM quotes_staging
+		ORDER BY quote, author, seq DESC
+		ON CONFLICT (quote, author) DO UPDATE SET
+			tags = EXCLUDED.tags,
+			popularity = EXCLUDED.popularity,
+			category = EXCLUDED.category,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE (quotes.tags, quotes.popularity, quotes.category)
+		      IS DISTINCT FROM (EXCLUDED.tags, EXCLUDED.popularity, EXCLUDED.category)
+		RETURNING (xmax = 0) AS inserted
+	`)
+	if err != nil {
+		return IngestResult{}, err
+	}
+
+	var result IngestResult
+	merged := 0
+	for rows.Next() {
+		var inserted bool
+		if err := rows.Scan(&inserted); err != nil {
+			rows.Close()
+			return IngestResult{}, err
+		}
+		if inserted {
+			result.Inserted++
+		} else {
+			result.Updated++
+		}
+		merged++
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return IngestResult{}, err
+	}
+
+	var distinct int
+	if err := tx.QueryRow(ctx, `SELECT COUNT(DISTINCT (quote, author)) FROM quotes_staging`).Scan(&distinct); err != nil {
+		return IngestResult{}, err
+	}
+	result.Duplicates = len(batch) - distinct
+	result.Unchanged = distinct - merged
+
+	// A dry run stages every batch in the same transaction
+	if _, err := tx.Exec(ctx, `DROP TABLE quotes_staging`); err != nil {
+		return IngestResult{}, err
+	}
+	return result, nil
+}
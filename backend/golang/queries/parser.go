@@ -0,0 +1,421 @@
+package queries
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ClauseType identifies which ParadeDB query function a clause is rendered with
+type ClauseType string
+
+const (
+	ClauseMatch  ClauseType = "match"
+	ClausePhrase ClauseType = "phrase"
+	ClauseTerm   ClauseType = "term"
+)
+
+// queryFields maps the field prefixes accepted in search input to BM25 index fields
+var queryFields = map[string]string{
+	"quote":    "quote",
+	"author":   "author",
+	"tag":      "tags",
+	"tags":     "tags",
+	"category": "category",
+}
+
+// QueryClause is a single condition of a parsed search query.
+// An empty Field means the default search field.
+type QueryClause struct {
+	Field  string     `json:"field,omitempty"`
+	Type   ClauseType `json:"type"`
+	Value  string     `json:"value"`
+	Tokens []string   `json:"tokens,omitempty"`
+}
+
+// ParsedQuery is the boolean tree built from the raw `q` search string
+type ParsedQuery struct {
+	Raw     string        `json:"raw"`
+	Must    []QueryClause `json:"must,omitempty"`
+	Should  []QueryClause `json:"should,omitempty"`
+	MustNot []QueryClause `json:"must_not,omitempty"`
+}
+
+// QueryParseError describes a malformed search query and where it went wrong
+type QueryParseError struct {
+	Message  string `json:"message"`
+	Position int    `json:"position"`
+	Token    string `json:"token"`
+}
+
+func (e *QueryParseError) Error() string {
+	return fmt.Sprintf("%s at position %d (%q)", e.Message, e.Position, e.Token)
+}
+
+// ParseQuery parses search input such as
+//
+//	courage author:churchill "never give up" -war tag:life
+//
+// Bare words are optional (should) clauses, quoted phrases and field-scoped
+// values are required (must), and a leading '-' excludes (must_not).
+// A leading '+' makes a bare word required.
+func ParseQuery(input string) (*ParsedQuery, error) {
+	p := &queryParser{input: input}
+	return p.parse()
+}
+
+type queryParser struct {
+	input string
+	pos   int
+}
+
+func (p *queryParser) parse() (*ParsedQuery, error) {
+	pq := &ParsedQuery{Raw: p.input}
+
+	for {
+		term, ok, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+
+		clause, ok := buildClause(term.field, term.value(p.input), term.phrase)
+		if !ok {
+			if term.field != "" || term.phrase {
+				return nil, &QueryParseError{Message: "value contains no searchable terms", Position: term.start, Token: p.input[term.start:p.pos]}
+			}
+			// Bare punctuation carries nothing to search for
+			continue
+		}
+
+		switch {
+		case term.operator == '-':
+			pq.MustNot = append(pq.MustNot, clause)
+		case term.operator == '+' || term.field != "" || term.phrase:
+			pq.Must = append(pq.Must, clause)
+		default:
+			pq.Should = append(pq.Should, clause)
+		}
+	}
+
+	if len(pq.Must) == 0 && len(pq.Should) == 0 {
+		return nil, &QueryParseError{Message: "query must contain at least one search term", Position: 0, Token: p.input}
+	}
+
+	return pq, nil
+}
+
+// queryTerm is one operator, field and value of the input, located by byte
+// offsets so the input can be rewritten around it
+type queryTerm struct {
+	start      int
+	operator   byte
+	field      string
+	valueStart int
+	valueEnd   int
+	phrase     bool
+}
+
+// value is the term's text, without the quotes of a phrase
+func (t queryTerm) value(input string) string {
+	return input[t.valueStart:t.valueEnd]
+}
+
+// next scans the term at the current position. It reports false once the
+// input is exhausted.
+func (p *queryParser) next() (queryTerm, bool, error) {
+	for p.pos < len(p.input) && isQuerySpace(p.input[p.pos]) {
+		p.pos++
+	}
+	if p.pos >= len(p.input) {
+		return queryTerm{}, false, nil
+	}
+
+	term := queryTerm{start: p.pos}
+
+	// Optional +/- operator
+	if c := p.input[p.pos]; c == '-' || c == '+' {
+		term.operator = c
+		p.pos++
+		if p.pos >= len(p.input) || isQuerySpace(p.input[p.pos]) {
+			return queryTerm{}, false, &QueryParseError{Message: "operator must be followed by a term", Position: term.start, Token: string(c)}
+		}
+	}
+
+	// Optional field: prefix
+	if name, ok := p.peekField(); ok {
+		indexField, known := queryFields[strings.ToLower(name)]
+		if !known {
+			return queryTerm{}, false, &QueryParseError{Message: fmt.Sprintf("unknown field %q", name), Position: p.pos, Token: name + ":"}
+		}
+		term.field = indexField
+		p.pos += len(name) + 1
+		if p.pos >= len(p.input) || isQuerySpace(p.input[p.pos]) {
+			return queryTerm{}, false, &QueryParseError{Message: "missing value for field", Position: term.start, Token: p.input[term.start:p.pos]}
+		}
+	}
+
+	// Value: quoted phrase or bare word
+	if p.input[p.pos] == '"' {
+		quoteStart := p.pos
+		end := strings.IndexByte(p.input[quoteStart+1:], '"')
+		if end < 0 {
+			return queryTerm{}, false, &QueryParseError{Message: "unterminated quoted phrase", Position: quoteStart, Token: p.input[quoteStart:]}
+		}
+		term.valueStart, term.valueEnd = quoteStart+1, quoteStart+1+end
+		p.pos = quoteStart + end + 2
+		term.phrase = true
+	} else {
+		term.valueStart = p.pos
+		for p.pos < len(p.input) && !isQuerySpace(p.input[p.pos]) {
+			p.pos++
+		}
+		term.valueEnd = p.pos
+	}
+
+	return term, true, nil
+}
+
+// peekField reports whether the input at the current position starts with `name:`
+func (p *queryParser) peekField() (string, bool) {
+	end := p.pos
+	for end < len(p.input) && isFieldChar(p.input[end]) {
+		end++
+	}
+	if end == p.pos || end >= len(p.input) || p.input[end] != ':' {
+		return "", false
+	}
+	return p.input[p.pos:end], true
+}
+
+func buildClause(field, value string, phrase bool) (QueryClause, bool) {
+	tokens := analyzeTokens(value)
+	if len(tokens) == 0 {
+		return QueryClause{}, false
+	}
+
+	clause := QueryClause{Field: field, Value: strings.TrimSpace(value), Tokens: tokens}
+	switch {
+	case len(tokens) > 1 && (phrase || field == "tags" || field == "category"):
+		clause.Type = ClausePhrase
+	case field == "tags" || field == "category":
+		// Keyword-like fields match the exact indexed token
+		clause.Type = ClauseTerm
+		clause.Value = tokens[0]
+	default:
+		clause.Type = ClauseMatch
+	}
+	return clause, true
+}
+
+// QueryOptions controls how a parsed query is rendered to SQL
+type QueryOptions struct {
+	Boosts SearchBoosts
+	// FuzzyDistance caps the edit distance of fuzzy matching; 0 disables it
+	FuzzyDistance int
+}
+
+// ToSQL renders the query as a paradedb.boolean expression, numbering
+// placeholders from argIndex. Clauses without a field search every indexed
+// field weighted by boosts; boosts that weight no field fall back to the
+// quote field. It returns the expression and its arguments.
+func (pq *ParsedQuery) ToSQL(argIndex int, options QueryOptions) (string, []interface{}) {
+	if !options.Boosts.Valid() {
+		options.Boosts = SearchBoosts{Quote: 1}
+	}
+	var args []interface{}
+	bind := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", argIndex+len(args)-1)
+	}
+
+	render := func(clauses []QueryClause, scoring bool) string {
+		var parts []string
+		for _, clause := range clauses {
+			// Exclusions stay exact; fuzzy expansion only widens what can match
+			fuzzy := scoring && options.FuzzyDistance > 0 && clause.Type == ClauseMatch
+			var placeholders []string
+			if fuzzy {
+				for _, token := range clause.Tokens {
+					placeholders = append(placeholders, bind(token))
+				}
+			} else {
+				placeholders = []string{bind(clause.arg())}
+			}
+
+			fieldSQL := func(field string) string {
+				if fuzzy {
+					return clause.fuzzySQL(field, placeholders, options.FuzzyDistance)
+				}
+				return clause.toSQL(field, placeholders[0])
+			}
+
+			if clause.Field != "" {
+				parts = append(parts, fieldSQL(clause.Field))
+				continue
+			}
+
+			// Default field: expand across all indexed fields
+			var fieldParts []string
+			for _, fb := range options.Boosts.fields() {
+				if scoring && fb.boost <= 0 {
+					continue
+				}
+				fieldPart := fieldSQL(fb.field)
+				if scoring {
+					fieldPart = fmt.Sprintf("paradedb.boost(%s, %s)", formatBoost(fb.boost), fieldPart)
+				}
+				fieldParts = append(fieldParts, fieldPart)
+			}
+			if scoring {
+				parts = append(parts, "paradedb.boolean(should => ARRAY["+strings.Join(fieldParts, ", ")+"])")
+			} else {
+				// Exclusions must not match in any field
+				parts = append(parts, fieldParts...)
+			}
+		}
+		return "ARRAY[" + strings.Join(parts, ", ") + "]"
+	}
+
+	var sections []string
+	if len(pq.Must) == 0 && len(pq.Should) == 0 {
+		// Exclusions alone match nothing, so they apply to every quote
+		sections = append(sections, "must => ARRAY[paradedb.all()]")
+	}
+	if len(pq.Must) > 0 {
+		sections = append(sections, "must => "+render(pq.Must, true))
+	}
+	if len(pq.Should) > 0 {
+		sections = append(sections, "should => "+render(pq.Should, true))
+	}
+	if len(pq.MustNot) > 0 {
+		sections = append(sections, "must_not => "+render(pq.MustNot, false))
+	}
+
+	return "paradedb.boolean(" + strings.Join(sections, ", ") + ")", args
+}
+
+// HasFuzzyTerms reports whether fuzzy matching would change the query
+func (pq *ParsedQuery) HasFuzzyTerms() bool {
+	for _, clauses := range [][]QueryClause{pq.Must, pq.Should} {
+		for _, clause := range clauses {
+			if clause.Type != ClauseMatch {
+				continue
+			}
+			for _, token := range clause.Tokens {
+				if fuzzyDistance(token, MaxFuzzyDistance) > 0 {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// Text joins the values the query looks for, for embedding in semantic
+// search. Exclusions and field-scoped values are left out, since semantic
+// search applies them through Constraints; a query of only field-scoped
+// values is embedded from those.
+func (pq *ParsedQuery) Text() string {
+	var values, scoped []string
+	for _, clauses := range [][]QueryClause{pq.Must, pq.Should} {
+		for _, clause := range clauses {
+			if clause.Field != "" {
+				scoped = append(scoped, clause.Value)
+				continue
+			}
+			values = append(values, clause.Value)
+		}
+	}
+	if len(values) == 0 {
+		values = scoped
+	}
+	return strings.Join(values, " ")
+}
+
+// Constraints returns the part of the query that restricts which quotes can
+// match rather than describing them: field-scoped requirements and
+// exclusions. Semantic search ranks by meaning and applies these as a filter.
+// It returns nil when the query has none.
+func (pq *ParsedQuery) Constraints() *ParsedQuery {
+	constraints := &ParsedQuery{Raw: pq.Raw, MustNot: pq.MustNot}
+	for _, clause := range pq.Must {
+		if clause.Field != "" {
+			constraints.Must = append(constraints.Must, clause)
+		}
+	}
+	if len(constraints.Must) == 0 && len(constraints.MustNot) == 0 {
+		return nil
+	}
+	return constraints
+}
+
+func (c QueryClause) arg() interface{} {
+	if c.Type == ClausePhrase {
+		return c.Tokens
+	}
+	return c.Value
+}
+
+func (c QueryClause) toSQL(field, placeholder string) string {
+	switch c.Type {
+	case ClausePhrase:
+		return fmt.Sprintf("paradedb.phrase('%s', %s::text[])", field, placeholder)
+	case ClauseTerm:
+		return fmt.Sprintf("paradedb.term('%s', %s::text)", field, placeholder)
+	default:
+		return fmt.Sprintf("paradedb.match('%s', %s::text)", field, placeholder)
+	}
+}
+
+// fuzzySQL matches any of the clause tokens within an edit distance scaled to token length
+func (c QueryClause) fuzzySQL(field string, placeholders []string, maxDistance int) string {
+	parts := make([]string, len(c.Tokens))
+	for i, token := range c.Tokens {
+		parts[i] = fmt.Sprintf("paradedb.fuzzy_term('%s', %s::text, distance => %d, transposition_cost_one => true)",
+			field, placeholders[i], fuzzyDistance(token, maxDistance))
+	}
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	return "paradedb.boolean(should => ARRAY[" + strings.Join(parts, ", ") + "])"
+}
+
+// MaxFuzzyDistance is the largest edit distance the BM25 index supports
+const MaxFuzzyDistance = 2
+
+// fuzzyDistance allows more edits for longer tokens: none up to 3 characters,
+// one up to 6 and two beyond that, capped at maxDistance
+func fuzzyDistance(token string, maxDistance int) int {
+	distance := 2
+	switch n := utf8.RuneCountInString(token); {
+	case n <= 3:
+		distance = 0
+	case n <= 6:
+		distance = 1
+	}
+	return min(distance, maxDistance)
+}
+
+// analyzeTokens approximates the index's default tokenizer: lowercase and split on non-alphanumerics
+func analyzeTokens(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func isQuerySpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func isFieldChar(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '_'
+}
+
+func formatBoost(boost float64) string {
+	return strconv.FormatFloat(boost, 'f', -1, 64)
+}
@@ -0,0 +1,151 @@
+package queries
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestParseQuery(t *testing.T) {
+	pq, err := ParseQuery(`courage author:churchill "never give up" -war tag:life`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	wantMust := []QueryClause{
+		{Field: "author", Type: ClauseMatch, Value: "churchill", Tokens: []string{"churchill"}},
+		{Type: ClausePhrase, Value: "never give up", Tokens: []string{"never", "give", "up"}},
+		{Field: "tags", Type: ClauseTerm, Value: "life", Tokens: []string{"life"}},
+	}
+	wantShould := []QueryClause{{Type: ClauseMatch, Value: "courage", Tokens: []string{"courage"}}}
+	wantMustNot := []QueryClause{{Type: ClauseMatch, Value: "war", Tokens: []string{"war"}}}
+
+	if !reflect.DeepEqual(pq.Must, wantMust) {
+		t.Errorf("must = %+v, want %+v", pq.Must, wantMust)
+	}
+	if !reflect.DeepEqual(pq.Should, wantShould) {
+		t.Errorf("should = %+v, want %+v", pq.Should, wantShould)
+	}
+	if !reflect.DeepEqual(pq.MustNot, wantMustNot) {
+		t.Errorf("must_not = %+v, want %+v", pq.MustNot, wantMustNot)
+	}
+
+	sql, args := pq.ToSQL(1, QueryOptions{Boosts: SearchBoosts{Quote: 1, Author: 3}})
+	wantSQL := "paradedb.boolean(must => ARRAY[paradedb.match('author', $1::text), " +
+		"paradedb.boolean(should => ARRAY[paradedb.boost(1, paradedb.phrase('quote', $2::text[])), paradedb.boost(3, paradedb.phrase('author', $2::text[]))]), " +
+		"paradedb.term('tags', $3::text)], " +
+		"should => ARRAY[paradedb.boolean(should => ARRAY[paradedb.boost(1, paradedb.match('quote', $4::text)), paradedb.boost(3, paradedb.match('author', $4::text))])], " +
+		"must_not => ARRAY[paradedb.match('quote', $5::text), paradedb.match('author', $5::text), " +
+		"paradedb.match('tags', $5::text), paradedb.match('category', $5::text)])"
+	if sql != wantSQL {
+		t.Errorf("sql =\n%s\nwant\n%s", sql, wantSQL)
+	}
+	if len(args) != 5 {
+		t.Errorf("got %d args, want 5", len(args))
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		position int
+	}{
+		{`life "never give`, 5},
+		{`life author:`, 5},
+		{`genre:poetry`, 0},
+		{`courage - war`, 8},
+		{`-war`, 0},
+	}
+
+	for _, tt := range tests {
+		_, err := ParseQuery(tt.input)
+		var parseErr *QueryParseError
+		if !errors.As(err, &parseErr) {
+			t.Errorf("ParseQuery(%q) error = %v, want *QueryParseError", tt.input, err)
+			continue
+		}
+		if parseErr.Position != tt.position {
+			t.Errorf("ParseQuery(%q) position = %d, want %d", tt.input, parseErr.Position, tt.position)
+		}
+	}
+}
+
+func TestFuzzyDistance(t *testing.T) {
+	tests := []struct {
+		token       string
+		maxDistance int
+		want        int
+	}{
+		{"war", 2, 0},
+		{"smile", 2, 1},
+		{"perserverance", 2, 2},
+		{"perserverance", 1, 1},
+	}
+
+	for _, tt := range tests {
+		if got := fuzzyDistance(tt.token, tt.maxDistance); got != tt.want {
+			t.Errorf("fuzzyDistance(%q, %d) = %d, want %d", tt.token, tt.maxDistance, got, tt.want)
+		}
+	}
+}
+
+func TestQueryConstraints(t *testing.T) {
+	pq, _ := ParseQuery(`courage author:churchill "never give up" -war`)
+	if text := pq.Text(); text != "never give up courage" {
+		t.Errorf("Text() = %q, want the unscoped values", text)
+	}
+
+	constraints := pq.Constraints()
+	if constraints == nil || len(constraints.Must) != 1 || constraints.Must[0].Field != "author" || len(constraints.Should) != 0 || len(constraints.MustNot) != 1 {
+		t.Fatalf("Constraints() = %+v, want author:churchill and -war", constraints)
+	}
+
+	// Exclusions alone apply to every quote
+	pq, _ = ParseQuery(`courage -war`)
+	sql, _ := pq.Constraints().ToSQL(1, QueryOptions{Boosts: SearchBoosts{Quote: 1}})
+	want := "paradedb.boolean(must => ARRAY[paradedb.all()], must_not => ARRAY[paradedb.match('quote', $1::text), " +
+		"paradedb.match('author', $1::text), paradedb.match('tags', $1::text), paradedb.match('category', $1::text)])"
+	if sql != want {
+		t.Errorf("exclusion constraints sql =\n%s\nwant\n%s", sql, want)
+	}
+
+	pq, _ = ParseQuery(`courage "never give up"`)
+	if constraints := pq.Constraints(); constraints != nil {
+		t.Errorf("Constraints() of an unscoped query = %+v, want nil", constraints)
+	}
+	pq, _ = ParseQuery(`author:churchill`)
+	if text := pq.Text(); text != "churchill" {
+		t.Errorf("Text() of a scoped-only query = %q, want churchill", text)
+	}
+}
+
+func TestToSQLWithoutBoostedFields(t *testing.T) {
+	pq, _ := ParseQuery("courage")
+	sql, _ := pq.ToSQL(1, QueryOptions{Boosts: SearchBoosts{}})
+	want := "paradedb.boolean(should => ARRAY[paradedb.boolean(should => ARRAY[paradedb.boost(1, paradedb.match('quote', $1::text))])])"
+	if sql != want {
+		t.Errorf("sql with all-zero boosts =\n%s\nwant\n%s", sql, want)
+	}
+
+	// An infinite boost would render as +Inf, which is not a SQL number
+	if sql, _ := pq.ToSQL(1, QueryOptions{Boosts: SearchBoosts{Quote: math.Inf(1)}}); sql != want {
+		t.Errorf("sql with an infinite boost =\n%s\nwant\n%s", sql, want)
+	}
+
+	for _, tc := range []struct {
+		boosts SearchBoosts
+		want   bool
+	}{
+		{SearchBoosts{}, false},
+		{SearchBoosts{Author: 2}, true},
+		{SearchBoosts{Quote: 1, Tags: -1}, false},
+		{SearchBoosts{Quote: math.Inf(1)}, false},
+		{SearchBoosts{Quote: 1, Author: math.NaN()}, false},
+		{DefaultSearchBoosts(), true},
+	} {
+		if got := tc.boosts.Valid(); got != tc.want {
+			t.Errorf("%+v.Valid() = %v, want %v", tc.boosts, got, tc.want)
+		}
+	}
+}
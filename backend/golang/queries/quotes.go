@@ -0,0 +1,315 @@
+package queries
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrQuoteNotFound is returned when a quote ID does not exist
+var ErrQuoteNotFound = errors.New("quote not found")
+
+// ErrDuplicateQuote is returned when a write would violate uq_quotes_quote_author
+var ErrDuplicateQuote = errors.New("quote by this author already exists")
+
+// ValidationError reports an invalid field in a quote write
+type ValidationError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// Quote field limits, matching the column sizes of the quotes table
+const (
+	maxQuoteAuthor   = 255
+	maxQuoteCategory = 100
+)
+
+// QuoteInput holds the editable fields of a quote. Nil fields are left
+// unchanged by updates; an empty category clears it.
+type QuoteInput struct {
+	Quote      *string   `json:"quote"`
+	Author     *string   `json:"author"`
+	Tags       *[]string `json:"tags"`
+	Popularity *float64  `json:"popularity"`
+	Category   *string   `json:"category"`
+}
+
+// Normalize applies the ingestion rules of normalize_quote_data: whitespace is
+// trimmed, quote and author must not be empty and popularity is clamped to
+// [0, 1]. Empty tags are dropped. Author and category must fit their columns.
+// When partial is false quote and author are required.
+func (in QuoteInput) Normalize(partial bool) (QuoteInput, error) {
+	out := QuoteInput{Popularity: in.Popularity}
+
+	for _, field := range []struct {
+		name   string
+		value  *string
+		dest   **string
+		maxLen int
+	}{
+		{"quote", in.Quote, &out.Quote, 0},
+		{"author", in.Author, &out.Author, maxQuoteAuthor},
+	} {
+		if field.value == nil {
+			if !partial {
+				return QuoteInput{}, &ValidationError{Field: field.name, Message: "is required"}
+			}
+			continue
+		}
+		trimmed := strings.TrimSpace(*field.value)
+		if trimmed == "" {
+			return QuoteInput{}, &ValidationError{Field: field.name, Message: "must not be empty"}
+		}
+		if field.maxLen > 0 && utf8.RuneCountInString(trimmed) > field.maxLen {
+			return QuoteInput{}, &ValidationError{Field: field.name, Message: fmt.Sprintf("must be at most %d characters", field.maxLen)}
+		}
+		*field.dest = &trimmed
+	}
+
+	if in.Tags != nil {
+		tags := []string{}
+		for _, tag := range *in.Tags {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+		out.Tags = &tags
+	}
+
+	if in.Popularity != nil {
+		if math.IsNaN(*in.Popularity) {
+			return QuoteInput{}, &ValidationError{Field: "popularity", Message: "must be a number"}
+		}
+		popularity := min(max(*in.Popularity, 0), 1)
+		out.Popularity = &popularity
+	}
+
+	if in.Category != nil {
+		category := strings.TrimSpace(*in.Category)
+		if utf8.RuneCountInString(category) > maxQuoteCategory {
+			return QuoteInput{}, &ValidationError{Field: "category", Message: fmt.Sprintf("must be at most %d characters", maxQuoteCategory)}
+		}
+		out.Category = &category
+	}
+
+	return out, nil
+}
+
+// MaxBatchIDs caps how many quotes can be hydrated in one batch request
+const MaxBatchIDs = 100
+
+// QuoteBatchResponse represents the response for batch quote lookup,
+// with quotes in the order requested and unknown IDs listed separately
+type QuoteBatchResponse struct {
+	Quotes  []Quote `json:"quotes"`
+	Missing []int   `json:"missing"`
+}
+
+type QuoteQueries struct {
+	db           *pgxpool.Pool
+	shareBaseURL string
+}
+
+func NewQuoteQueries(db *pgxpool.Pool, shareBaseURL string) *QuoteQueries {
+	return &QuoteQueries{db: db, shareBaseURL: strings.TrimRight(shareBaseURL, "/")}
+}
+
+const quoteColumns = `id, quote, author, category, tags, popularity, created_at, updated_at`
+
+// GetByID returns a single quote with all of its fields, or ErrQuoteNotFound
+func (qq *QuoteQueries) GetByID(ctx context.Context, id int) (Quote, error) {
+	sql := fmt.Sprintf(`
+		SELECT %s
+		FROM quotes
+		WHERE id = $1
+	`, quoteColumns)
+
+	q, err := qq.scanQuote(qq.db.QueryRow(ctx, sql, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Quote{}, ErrQuoteNotFound
+	}
+	return q, err
+}
+
+// GetByIDs returns the quotes for ids in the order given. Duplicate IDs are
+// returned once and IDs that do not exist are reported as missing.
+func (qq *QuoteQueries) GetByIDs(ctx context.Context, ids []int) (QuoteBatchResponse, error) {
+	sql := fmt.Sprintf(`
+		SELECT %s
+		FROM quotes
+		WHERE id = ANY($1)
+	`, quoteColumns)
+
+	rows, err := qq.db.Query(ctx, sql, ids)
+	if err != nil {
+		return QuoteBatchResponse{}, err
+	}
+	defer rows.Close()
+
+	found := make(map[int]Quote, len(ids))
+	for rows.Next() {
+		q, err := qq.scanQuote(rows)
+		if err != nil {
+			return QuoteBatchResponse{}, err
+		}
+		found[q.ID] = q
+	}
+	if err := rows.Err(); err != nil {
+		return QuoteBatchResponse{}, err
+	}
+
+	response := QuoteBatchResponse{Quotes: []Quote{}, Missing: []int{}}
+	seen := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if q, ok := found[id]; ok {
+			response.Quotes = append(response.Quotes, q)
+		} else {
+			response.Missing = append(response.Missing, id)
+		}
+	}
+
+	return response, nil
+}
+
+// CreateQuote validates and inserts a new quote. Returns ErrDuplicateQuote
+// when the same quote by the same author already exists.
+func (qq *QuoteQueries) CreateQuote(ctx context.Context, input QuoteInput) (Quote, error) {
+	input, err := input.Normalize(false)
+	if err != nil {
+		return Quote{}, err
+	}
+
+	tags := []string{}
+	if input.Tags != nil {
+		tags = *input.Tags
+	}
+
+	sql := fmt.Sprintf(`
+		INSERT INTO quotes (quote, author, tags, popularity, category, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		RETURNING %s
+	`, quoteColumns)
+
+	q, err := qq.scanQuote(qq.db.QueryRow(ctx, sql, *input.Quote, *input.Author, tags, input.Popularity, input.Category))
+	return q, translateWriteError(err)
+}
+
+// UpdateQuote validates and applies the fields set in input, bumping
+// updated_at. Returns ErrQuoteNotFound or ErrDuplicateQuote.
+func (qq *QuoteQueries) UpdateQuote(ctx context.Context, id int, input QuoteInput) (Quote, error) {
+	input, err := input.Normalize(true)
+	if err != nil {
+		return Quote{}, err
+	}
+
+	var setClauses []string
+	var args []interface{}
+	argIndex := 1
+
+	set := func(column string, value interface{}) {
+		setClauses = append(setClauses, fmt.Sprintf("%s = $%d", column, argIndex))
+		args = append(args, value)
+		argIndex++
+	}
+	if input.Quote != nil {
+		set("quote", *input.Quote)
+		// The old embedding no longer describes the quote; it is recomputed in the background
+		setClauses = append(setClauses, "embedding = NULL", "embedding_model = NULL")
+	}
+	if input.Author != nil {
+		set("author", *input.Author)
+	}
+	if input.Tags != nil {
+		set("tags", *input.Tags)
+	}
+	if input.Popularity != nil {
+		set("popularity", *input.Popularity)
+	}
+	if input.Category != nil {
+		setClauses = append(setClauses, fmt.Sprintf("category = NULLIF($%d, '')", argIndex))
+		args = append(args, *input.Category)
+		argIndex++
+	}
+	if len(setClauses) == 0 {
+		return Quote{}, &ValidationError{Field: "body", Message: "no fields to update"}
+	}
+
+	sql := fmt.Sprintf(`
+		UPDATE quotes
+		SET %s, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $%d
+		RETURNING %s
+	`, strings.Join(setClauses, ", "), argIndex, quoteColumns)
+
+	args = append(args, id)
+
+	q, err := qq.scanQuote(qq.db.QueryRow(ctx, sql, args...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Quote{}, ErrQuoteNotFound
+	}
+	return q, translateWriteError(err)
+}
+
+// DeleteQuote removes a quote. Returns ErrQuoteNotFound when it does not exist.
+func (qq *QuoteQueries) DeleteQuote(ctx context.Context, id int) error {
+	tag, err := qq.db.Exec(ctx, `DELETE FROM quotes WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrQuoteNotFound
+	}
+	return nil
+}
+
+// translateWriteError maps the quote/author unique violation to ErrDuplicateQuote
+func translateWriteError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "uq_quotes_quote_author" {
+		return ErrDuplicateQuote
+	}
+	return err
+}
+
+// ShareURL returns the canonical public URL of a quote
+func (qq *QuoteQueries) ShareURL(id int) string {
+	return fmt.Sprintf("%s/quotes/%d", qq.shareBaseURL, id)
+}
+
+func (qq *QuoteQueries) scanQuote(row pgx.Row) (Quote, error) {
+	var q Quote
+	var createdAt, updatedAt *time.Time
+
+	err := row.Scan(&q.ID, &q.Quote, &q.Author, &q.Category, &q.Tags, &q.Popularity, &createdAt, &updatedAt)
+	if err != nil {
+		return Quote{}, err
+	}
+
+	if createdAt != nil {
+		createdAtStr := createdAt.Format(time.RFC3339)
+		q.CreatedAt = &createdAtStr
+	}
+	if updatedAt != nil {
+		updatedAtStr := updatedAt.Format(time.RFC3339)
+		q.UpdatedAt = &updatedAtStr
+	}
+	q.ShareURL = qq.ShareURL(q.ID)
+
+	return q, nil
+}
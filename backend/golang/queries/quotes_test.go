@@ -0,0 +1,63 @@
+package queries
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestQuoteInputNormalize(t *testing.T) {
+	quote, author, category := "  Be yourself. ", " Oscar Wilde ", " life "
+	popularity := 1.5
+	tags := []string{" wit ", "", "  "}
+	in, err := QuoteInput{Quote: &quote, Author: &author, Category: &category, Popularity: &popularity, Tags: &tags}.Normalize(false)
+	if err != nil {
+		t.Fatalf("Normalize: %v", err)
+	}
+	if *in.Quote != "Be yourself." || *in.Author != "Oscar Wilde" || *in.Category != "life" || *in.Popularity != 1 {
+		t.Errorf("Normalize = quote %q, author %q, category %q, popularity %v", *in.Quote, *in.Author, *in.Category, *in.Popularity)
+	}
+	if !reflect.DeepEqual(*in.Tags, []string{"wit"}) {
+		t.Errorf("tags = %q, want [wit]", *in.Tags)
+	}
+
+	negative := -0.5
+	if in, _ := (QuoteInput{Quote: &quote, Author: &author, Popularity: &negative}).Normalize(false); *in.Popularity != 0 {
+		t.Errorf("popularity -0.5 clamped to %v, want 0", *in.Popularity)
+	}
+
+	// Updates leave unset fields alone
+	if in, err := (QuoteInput{Category: &category}).Normalize(true); err != nil || in.Quote != nil || in.Author != nil {
+		t.Errorf("partial Normalize = %+v, %v", in, err)
+	}
+
+	blank := "   "
+	nan := math.NaN()
+	longAuthor := strings.Repeat("é", maxQuoteAuthor+1)
+	fitsAuthor := strings.Repeat("é", maxQuoteAuthor)
+	longCategory := strings.Repeat("a", maxQuoteCategory+1)
+	for _, tc := range []struct {
+		name  string
+		input QuoteInput
+		field string
+	}{
+		{"missing quote", QuoteInput{Author: &author}, "quote"},
+		{"missing author", QuoteInput{Quote: &quote}, "author"},
+		{"blank quote", QuoteInput{Quote: &blank, Author: &author}, "quote"},
+		{"NaN popularity", QuoteInput{Quote: &quote, Author: &author, Popularity: &nan}, "popularity"},
+		{"long author", QuoteInput{Quote: &quote, Author: &longAuthor}, "author"},
+		{"long category", QuoteInput{Quote: &quote, Author: &author, Category: &longCategory}, "category"},
+	} {
+		var validationErr *ValidationError
+		if _, err := tc.input.Normalize(false); !errors.As(err, &validationErr) || validationErr.Field != tc.field {
+			t.Errorf("%s: Normalize error = %v, want a ValidationError on %s", tc.name, err, tc.field)
+		}
+	}
+
+	// Limits count characters, not bytes
+	if _, err := (QuoteInput{Quote: &quote, Author: &fitsAuthor}).Normalize(false); err != nil {
+		t.Errorf("Normalize rejected a %d-character author: %v", maxQuoteAuthor, err)
+	}
+}
@@ -0,0 +1,96 @@
+package queries
+
+import "fmt"
+
+// seededHashSQL hashes an int4 id expression with seed. Ordering by it is a
+// random order that every request with the same seed sees, so it can be paged.
+func seededHashSQL(id string, seed int64) string {
+	return fmt.Sprintf("hashint4extended(%s, %d)", id, seed)
+}
+
+// seededOrderBy orders quotes by their seeded hash, with id as a tie-breaker
+func seededOrderBy(seed int64, direction string) string {
+	return fmt.Sprintf("ORDER BY %s %s, id %s", seededHashSQL("id", seed), direction, direction)
+}
+
+// seededSeekCondition renders the predicate for rows after the quote id in
+// seeded random order. The cursor's hash is recomputed from its id, so the
+// cursor only needs to carry the id and seed.
+func seededSeekCondition(args *Args, seed int64, desc bool, id int) string {
+	op := ">"
+	if desc {
+		op = "<"
+	}
+	idArg := args.Add(id)
+	return fmt.Sprintf("(%s, id) %s (%s, %s::int)",
+		seededHashSQL("id", seed), op, seededHashSQL(idArg+"::int", seed), idArg)
+}
+
+// sampleSQL draws a random page by probing random ids between the smallest
+// and largest id and seeking each to the first quote at or after it, which
+// reads a few index entries instead of sorting the table. Probes that land in
+// the same gap find the same quote, so more probes than rows are drawn; when
+// that still leaves the sample short, a second round of fresh probes seeks
+// past the quotes already taken. The second round only runs when needed.
+const sampleSQL = `
+	WITH bounds AS (
+		SELECT MIN(id) AS lo, MAX(id) AS hi FROM quotes
+	),
+	first_round AS (
+		SELECT hit.id, MIN(probes.n) AS n
+		FROM (
+			SELECT lo + floor(random() * (hi - lo + 1))::int AS target, n
+			FROM bounds, generate_series(1, %[1]s) AS n
+			WHERE lo IS NOT NULL
+		) probes
+		CROSS JOIN LATERAL (
+			SELECT id FROM quotes WHERE id >= probes.target ORDER BY id LIMIT 1
+		) hit
+		GROUP BY hit.id
+		ORDER BY n
+		LIMIT %[2]s
+	),
+	second_round AS (
+		SELECT hit.id, %[1]s + MIN(probes.n) AS n
+		FROM (
+			SELECT lo + floor(random() * (hi - lo + 1))::int AS target, n
+			FROM bounds, generate_series(1, %[1]s) AS n
+			WHERE lo IS NOT NULL AND (SELECT COUNT(*) FROM first_round) < %[2]s
+		) probes
+		CROSS JOIN LATERAL (
+			SELECT id FROM quotes
+			WHERE id >= probes.target AND id NOT IN (SELECT id FROM first_round)
+			ORDER BY id LIMIT 1
+		) hit
+		GROUP BY hit.id
+		ORDER BY n
+		LIMIT %[2]s - (SELECT COUNT(*) FROM first_round)
+	)
+	SELECT quotes.id, quote, author, category, tags, popularity, created_at
+	FROM (
+		SELECT id, n FROM first_round
+		UNION ALL
+		SELECT id, n FROM second_round
+	) sampled
+	JOIN quotes ON quotes.id = sampled.id
+	ORDER BY sampled.n
+`
+
+// sampleProbes is how many ids are probed for a sample of limit rows
+func sampleProbes(limit int) int {
+	return limit*4 + 16
+}
+
+// buildSampleStatement renders sampleSQL for a page of limit rows
+func buildSampleStatement(args *Args, limit int) string {
+	return fmt.Sprintf(sampleSQL, args.Add(sampleProbes(limit)), args.Add(limit))
+}
+
+// sampleable reports whether a browse request is served by sampling: the
+// first page of an unseeded random order without filters. Such a page has no
+// order to keep, so any random rows will do.
+func (bq *BrowseQueries) sampleable(params BrowseParams) bool {
+	sort, _ := bq.resolveSort(params.Sort, params.Order)
+	return sort == "random" && params.Seed == nil && params.Cursor == "" && params.Page <= 1 &&
+		len(NewFilter(params).Conditions(&Args{})) == 0
+}
@@ -0,0 +1,75 @@
+package queries
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSeededRandomOrder(t *testing.T) {
+	bq := NewBrowseQueries(nil, DefaultSearchConfig())
+	seed := int64(42)
+
+	if got, want := bq.buildOrderBy("random", "asc", &seed), "ORDER BY hashint4extended(id, 42) ASC, id ASC"; got != want {
+		t.Errorf("seeded buildOrderBy = %q, want %q", got, want)
+	}
+	if got := bq.buildOrderBy("random", "", nil); got != "ORDER BY RANDOM()" {
+		t.Errorf("unseeded buildOrderBy = %q", got)
+	}
+
+	// A seeded page hands out a cursor that only continues the same seed
+	params := BrowseParams{Sort: "random", Order: "asc", Seed: &seed}
+	params.Cursor = bq.nextCursor(params, Quote{ID: 7}, nil)
+	if params.Cursor == "" {
+		t.Fatalf("nextCursor for a seeded random page is empty")
+	}
+
+	args := &Args{}
+	sql, err := bq.buildSeekCondition(params, args)
+	want := "(hashint4extended(id, 42), id) > (hashint4extended($1::int, 42), $1::int)"
+	if err != nil || sql != want || args.Values()[0] != 7 {
+		t.Errorf("buildSeekCondition = %q, %v, %v; want %q", sql, args.Values(), err, want)
+	}
+
+	other := int64(43)
+	for name, p := range map[string]BrowseParams{
+		"other seed": {Sort: "random", Order: "asc", Seed: &other, Cursor: params.Cursor},
+		"no seed":    {Sort: "random", Order: "asc", Cursor: params.Cursor},
+	} {
+		if _, err := bq.buildSeekCondition(p, &Args{}); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("%s: buildSeekCondition error = %v, want ErrInvalidCursor", name, err)
+		}
+	}
+	if bq.nextCursor(BrowseParams{Sort: "random"}, Quote{ID: 7}, nil) != "" {
+		t.Errorf("nextCursor for an unseeded random page is not empty")
+	}
+}
+
+func TestSampleable(t *testing.T) {
+	bq := NewBrowseQueries(nil, DefaultSearchConfig())
+	seed := int64(1)
+
+	if !bq.sampleable(BrowseParams{Sort: "random", Page: 1, Limit: 20}) {
+		t.Errorf("an unfiltered random first page is not sampled")
+	}
+	for name, params := range map[string]BrowseParams{
+		"popularity": {Sort: "popularity", Page: 1},
+		"seeded":     {Sort: "random", Page: 1, Seed: &seed},
+		"page 2":     {Sort: "random", Page: 2},
+		"filtered":   {Sort: "random", Page: 1, Tags: []string{"life"}},
+	} {
+		if bq.sampleable(params) {
+			t.Errorf("%s: request is sampled", name)
+		}
+	}
+
+	args := &Args{}
+	sql := buildSampleStatement(args, 20)
+	if !strings.Contains(sql, "generate_series(1, $1)") || !strings.Contains(sql, "WHERE id >= probes.target ORDER BY id LIMIT 1") ||
+		!strings.Contains(sql, "(SELECT COUNT(*) FROM first_round) < $2") || strings.Contains(sql, "ORDER BY random()") {
+		t.Errorf("sample SQL:\n%s", sql)
+	}
+	if values := args.Values(); values[0] != sampleProbes(20) || values[1] != 20 {
+		t.Errorf("sample args = %v", values)
+	}
+}
@@ -0,0 +1,232 @@
+package queries
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Search sort modes
+const (
+	SortRelevance  = "relevance"
+	SortPopularity = "popularity"
+	SortBlended    = "blended"
+	SortCreatedAt  = "created_at"
+)
+
+// RankingWeights configures the blended search ranking:
+//
+//	blended = Score*normalized_score + Popularity*popularity + Recency*recency
+//
+// normalized_score is the BM25 score divided by the best score of the search,
+// so it lies in [0, 1] like popularity. recency halves every RecencyHalfLife
+// from 1 for a quote created now.
+type RankingWeights struct {
+	Score           float64
+	Popularity      float64
+	Recency         float64
+	RecencyHalfLife time.Duration
+}
+
+// DefaultRankingWeights returns the blend used when none is configured
+func DefaultRankingWeights() RankingWeights {
+	return RankingWeights{
+		Score:           1.0,
+		Popularity:      0.5,
+		Recency:         0,
+		RecencyHalfLife: 365 * 24 * time.Hour,
+	}
+}
+
+// ScoreComponents explains how a search result was ranked
+type ScoreComponents struct {
+	BM25            float64  `json:"bm25"`
+	NormalizedScore float64  `json:"normalized_score"`
+	Popularity      *float64 `json:"popularity"`
+	Recency         *float64 `json:"recency"`
+	Blended         float64  `json:"blended"`
+}
+
+// scoredSearchSQL selects the matches of a search with their BM25 score and
+// snippet, both taken in the BM25 scan that produces them. It has no window or
+// aggregate, so Postgres pulls it up into the page query and ParadeDB can
+// serve ORDER BY ... LIMIT as a top-k search.
+const scoredSearchSQL = `
+	SELECT id, quote, author, category, tags, popularity, created_at,
+	       paradedb.score(id)::float8 AS relevance,
+	       paradedb.snippet(quote) AS highlighted_quote
+	FROM quotes
+	%s
+`
+
+// unblendedSearchSQL leaves the blend components of scored matches empty
+const unblendedSearchSQL = `
+	SELECT matched.*,
+	       NULL::float8 AS normalized_score,
+	       NULL::float8 AS recency,
+	       NULL::float8 AS blended_score
+	FROM (%s) matched
+`
+
+// blendedSearchSQL derives the blend components of scored matches. The score
+// normalization needs the best score over all matches, so every match is
+// scored before the page is ordered and cut; scores and snippets pass through
+// from the scan.
+const blendedSearchSQL = `
+	SELECT scored.*,
+	       %[2]s::float8 * COALESCE(normalized_score, 0)
+	       + %[3]s::float8 * COALESCE(popularity, 0)::float8
+	       + %[4]s::float8 * COALESCE(recency, 0) AS blended_score
+	FROM (
+		SELECT matched.*,
+		       relevance / NULLIF(MAX(relevance) OVER (), 0) AS normalized_score,
+		       power(0.5::float8, GREATEST(EXTRACT(EPOCH FROM (LOCALTIMESTAMP - created_at)), 0)::float8 / %[5]s::float8) AS recency
+		FROM (%[1]s) matched
+	) scored
+`
+
+// buildRankedSearch renders the ranked matches of a search with whereClause.
+// Only blended rankings pay for blendedSearchSQL; the others keep the plain
+// scored search.
+func buildRankedSearch(args *Args, whereClause string, weights RankingWeights, blended bool) string {
+	scored := fmt.Sprintf(scoredSearchSQL, whereClause)
+	if !blended {
+		return fmt.Sprintf(unblendedSearchSQL, scored)
+	}
+	return fmt.Sprintf(blendedSearchSQL, scored,
+		args.Add(weights.Score), args.Add(weights.Popularity), args.Add(weights.Recency),
+		args.Add(weights.RecencyHalfLife.Seconds()))
+}
+
+// rankedSearchColumns are the columns a page reads back from buildRankedSearch
+const rankedSearchColumns = `id, quote, author, category, tags, popularity, created_at,
+	       relevance, highlighted_quote, normalized_score, recency, blended_score`
+
+// searchRanking is a validated search sort
+type searchRanking struct {
+	sort  string
+	order string
+}
+
+// resolveSearchRanking validates the sort and order of a search. Relevance is
+// the default; relevance and blended always rank best first.
+func resolveSearchRanking(sort, order string) searchRanking {
+	switch sort {
+	case SortRelevance, SortPopularity, SortBlended, SortCreatedAt:
+	default:
+		sort = SortRelevance
+	}
+	if order != "asc" && order != "desc" {
+		order = "desc"
+	}
+	if sort == SortRelevance || sort == SortBlended {
+		order = "desc"
+	}
+	return searchRanking{sort: sort, order: order}
+}
+
+// column is the rankedSearchSQL column the ranking orders by
+func (r searchRanking) column() string {
+	switch r.sort {
+	case SortBlended:
+		return "blended_score"
+	case SortRelevance:
+		return "relevance"
+	default:
+		return r.sort
+	}
+}
+
+// cast is the SQL type cursor values are compared as
+func (r searchRanking) cast() string {
+	switch r.sort {
+	case SortCreatedAt:
+		return "timestamp"
+	case SortPopularity:
+		return "numeric"
+	default:
+		return "float8"
+	}
+}
+
+// orderBy orders by the ranking column with id as a tie-breaker
+func (r searchRanking) orderBy() string {
+	direction := strings.ToUpper(r.order)
+	return fmt.Sprintf("ORDER BY %s %s NULLS LAST, id %s", r.column(), direction, direction)
+}
+
+// seek decodes a cursor into a predicate for the rows after it
+func (r searchRanking) seek(args *Args, token string) (string, error) {
+	cursor, err := decodeCursor(token, r.sort, r.order)
+	if err != nil {
+		return "", err
+	}
+
+	var value interface{}
+	if r.sort == SortCreatedAt {
+		if cursor.Time != nil {
+			value = *cursor.Time
+		}
+	} else if cursor.Value != nil {
+		value = *cursor.Value
+	} else if r.sort != SortPopularity {
+		// Only popularity can be NULL
+		return "", ErrInvalidCursor
+	}
+
+	return seekCondition(args, r.column(), r.cast(), r.order == "desc", value, cursor.ID), nil
+}
+
+// cursor encodes the position after a ranked row
+func (r searchRanking) cursor(row rankedRow) string {
+	cursor := pageCursor{Sort: r.sort, Order: r.order, ID: row.quote.ID}
+	switch r.sort {
+	case SortCreatedAt:
+		cursor.Time = row.createdAt
+	case SortPopularity:
+		cursor.Value = row.quote.Popularity
+	case SortBlended:
+		blended := row.components.Blended
+		cursor.Value = &blended
+	default:
+		relevance := row.components.BM25
+		cursor.Value = &relevance
+	}
+	return cursor.encode()
+}
+
+// rankedRow is a quote read from rankedSearchSQL with its ranking inputs
+type rankedRow struct {
+	quote      Quote
+	createdAt  *time.Time
+	components ScoreComponents
+}
+
+// scanRankedRow reads one row of rankedSearchColumns
+func scanRankedRow(rows interface{ Scan(...interface{}) error }) (rankedRow, error) {
+	var row rankedRow
+	q := &row.quote
+	var relevance, normalized, blended *float64
+	err := rows.Scan(&q.ID, &q.Quote, &q.Author, &q.Category, &q.Tags, &q.Popularity, &row.createdAt,
+		&relevance, &q.HighlightedQuote, &normalized, &row.components.Recency, &blended)
+	if err != nil {
+		return rankedRow{}, err
+	}
+
+	if row.createdAt != nil {
+		createdAtStr := row.createdAt.Format(time.RFC3339)
+		q.CreatedAt = &createdAtStr
+	}
+	if relevance != nil {
+		q.Relevance = *relevance
+		row.components.BM25 = *relevance
+	}
+	if normalized != nil {
+		row.components.NormalizedScore = *normalized
+	}
+	if blended != nil {
+		row.components.Blended = *blended
+	}
+	row.components.Popularity = q.Popularity
+	return row, nil
+}
@@ -0,0 +1,94 @@
+package queries
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResolveSearchRanking(t *testing.T) {
+	for _, tc := range []struct {
+		sort, order string
+		want        searchRanking
+		orderBy     string
+	}{
+		{"", "", searchRanking{SortRelevance, "desc"}, "ORDER BY relevance DESC NULLS LAST, id DESC"},
+		{"random", "asc", searchRanking{SortRelevance, "desc"}, "ORDER BY relevance DESC NULLS LAST, id DESC"},
+		{"blended", "asc", searchRanking{SortBlended, "desc"}, "ORDER BY blended_score DESC NULLS LAST, id DESC"},
+		{"popularity", "asc", searchRanking{SortPopularity, "asc"}, "ORDER BY popularity ASC NULLS LAST, id ASC"},
+		{"created_at", "sideways", searchRanking{SortCreatedAt, "desc"}, "ORDER BY created_at DESC NULLS LAST, id DESC"},
+	} {
+		got := resolveSearchRanking(tc.sort, tc.order)
+		if got != tc.want {
+			t.Errorf("resolveSearchRanking(%q, %q) = %+v, want %+v", tc.sort, tc.order, got, tc.want)
+		}
+		if orderBy := got.orderBy(); orderBy != tc.orderBy {
+			t.Errorf("orderBy for %+v = %q, want %q", got, orderBy, tc.orderBy)
+		}
+	}
+}
+
+func TestSearchRankingCursor(t *testing.T) {
+	popularity := 0.25
+	created := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	row := rankedRow{
+		quote:      Quote{ID: 9, Popularity: &popularity},
+		createdAt:  &created,
+		components: ScoreComponents{BM25: 3.5, Blended: 1.125},
+	}
+
+	for _, tc := range []struct {
+		ranking searchRanking
+		want    string
+	}{
+		{resolveSearchRanking(SortRelevance, ""), "(relevance IS NULL OR relevance < $1::float8 OR (relevance = $1::float8 AND id < $2))"},
+		{resolveSearchRanking(SortBlended, ""), "(blended_score IS NULL OR blended_score < $1::float8 OR (blended_score = $1::float8 AND id < $2))"},
+		{resolveSearchRanking(SortPopularity, "asc"), "(popularity IS NULL OR popularity > $1::numeric OR (popularity = $1::numeric AND id > $2))"},
+		{resolveSearchRanking(SortCreatedAt, "desc"), "(created_at IS NULL OR created_at < $1::timestamp OR (created_at = $1::timestamp AND id < $2))"},
+	} {
+		args := &Args{}
+		seek, err := tc.ranking.seek(args, tc.ranking.cursor(row))
+		if err != nil {
+			t.Fatalf("seek for %+v: %v", tc.ranking, err)
+		}
+		if seek != tc.want {
+			t.Errorf("seek for %+v =\n  %s\nwant\n  %s", tc.ranking, seek, tc.want)
+		}
+	}
+
+	// A cursor from another ranking is rejected
+	token := resolveSearchRanking(SortBlended, "").cursor(row)
+	if _, err := resolveSearchRanking(SortRelevance, "").seek(&Args{}, token); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("seek with a blended cursor under relevance error = %v, want ErrInvalidCursor", err)
+	}
+}
+
+func TestPageSQLBlendsOnlyWhenNeeded(t *testing.T) {
+	query, _ := ParseQuery("courage")
+	sq := NewSearchQueries(nil, DefaultSearchConfig(), nil, nil)
+
+	for _, tc := range []struct {
+		params  BrowseParams
+		blended bool
+	}{
+		{BrowseParams{Page: 1, Limit: 20}, false},
+		{BrowseParams{Page: 1, Limit: 20, Sort: SortPopularity}, false},
+		{BrowseParams{Page: 1, Limit: 20, Sort: SortBlended}, true},
+		{BrowseParams{Page: 1, Limit: 20, Debug: true}, true},
+	} {
+		sql, _, err := sq.pageSQL(query, tc.params)
+		if err != nil {
+			t.Fatalf("pageSQL(%+v): %v", tc.params, err)
+		}
+		if blended := strings.Contains(sql, "OVER ()"); blended != tc.blended {
+			t.Errorf("pageSQL(%+v) normalizes over every match = %v, want %v:\n%s", tc.params, blended, tc.blended, sql)
+		}
+		// Snippets are taken next to the score, in the BM25 scan itself
+		scan := sql[strings.Index(sql, "paradedb.score(id)"):]
+		scan = scan[:strings.Index(scan, "FROM quotes")]
+		if strings.Count(sql, "paradedb.snippet") != 1 || !strings.Contains(scan, "paradedb.snippet(quote) AS highlighted_quote") {
+			t.Errorf("pageSQL(%+v) highlights outside the BM25 scan:\n%s", tc.params, sql)
+		}
+	}
+}
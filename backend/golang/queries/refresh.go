@@ -0,0 +1,25 @@
+package queries
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// RefreshPeriodically calls refresh every interval until ctx is cancelled.
+// Failures are logged and the previous data is kept.
+func RefreshPeriodically(ctx context.Context, name string, interval time.Duration, refresh func(context.Context) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := refresh(ctx); err != nil {
+				log.Printf("%s index refresh failed: %v", name, err)
+			}
+		}
+	}
+}
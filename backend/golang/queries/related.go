@@ -0,0 +1,159 @@
+package queries
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Boosts applied to each similarity signal when ranking related quotes
+const (
+	relatedTextBoost     = 1.0
+	relatedTagBoost      = 2.0
+	relatedCategoryBoost = 1.5
+)
+
+// RelatedResponse represents the response for related quotes API
+type RelatedResponse struct {
+	SourceID      int           `json:"source_id"`
+	Quotes        []Quote       `json:"quotes"`
+	Pagination    Pagination    `json:"pagination"`
+	ActiveFilters ActiveFilters `json:"active_filters"`
+}
+
+// GetRelated returns quotes similar to the quote with the given ID, ranked by
+// BM25 text similarity plus shared tags and category. The source quote is
+// never included. Returns ErrQuoteNotFound when the ID does not exist.
+func (sq *SearchQueries) GetRelated(ctx context.Context, id int, params BrowseParams) (RelatedResponse, error) {
+	source, err := sq.getSourceQuote(ctx, id)
+	if err != nil {
+		return RelatedResponse{}, err
+	}
+
+	rows, err := sq.BuildRelatedStatement(ctx, source, params)
+	if err != nil {
+		return RelatedResponse{}, err
+	}
+	defer rows.Close()
+
+	quotes := []Quote{}
+	for rows.Next() {
+		var q Quote
+		var tagsArray []string
+		var createdAt interface{}
+
+		err := rows.Scan(&q.ID, &q.Quote, &q.Author, &q.Category, &tagsArray, &q.Popularity, &createdAt, &q.Relevance)
+		if err != nil {
+			return RelatedResponse{}, err
+		}
+
+		q.Tags = tagsArray
+		if createdAt != nil {
+			if t, ok := createdAt.(time.Time); ok {
+				createdAtStr := t.Format(time.RFC3339)
+				q.CreatedAt = &createdAtStr
+			}
+		}
+		quotes = append(quotes, q)
+	}
+
+	if err := rows.Err(); err != nil {
+		return RelatedResponse{}, err
+	}
+
+	totalCount, err := sq.countRelated(ctx, source, params)
+	if err != nil {
+		return RelatedResponse{}, err
+	}
+
+	return RelatedResponse{
+		SourceID:      source.ID,
+		Quotes:        quotes,
+		Pagination:    sq.buildPagination(params.Page, params.Limit, totalCount),
+		ActiveFilters: sq.buildActiveFilters(params),
+	}, nil
+}
+
+func (sq *SearchQueries) BuildRelatedStatement(ctx context.Context, source Quote, params BrowseParams) (pgx.Rows, error) {
+	args := &Args{}
+	whereClause := relatedWhere(args, source, params)
+
+	// Calculate OFFSET
+	offset := (params.Page - 1) * params.Limit
+
+	sql := fmt.Sprintf(`
+		SELECT id, quote, author, category, tags, popularity, created_at,
+		       paradedb.score(id) as relevance
+		FROM quotes
+		%s
+		ORDER BY paradedb.score(id) DESC, popularity DESC NULLS LAST
+		LIMIT %s OFFSET %s
+	`, whereClause, args.Add(params.Limit), args.Add(offset))
+
+	return sq.db.Query(ctx, sql, args.Values()...)
+}
+
+// countRelated counts the quotes related to source that pass the request filters
+func (sq *SearchQueries) countRelated(ctx context.Context, source Quote, params BrowseParams) (int, error) {
+	args := &Args{}
+	sql := fmt.Sprintf(`
+		SELECT COUNT(*)
+		FROM quotes
+		%s
+	`, relatedWhere(args, source, params))
+
+	var count int
+	err := sq.db.QueryRow(ctx, sql, args.Values()...).Scan(&count)
+	return count, err
+}
+
+// relatedWhere renders the WHERE clause matching quotes similar to source
+// under the request filters, always excluding the source itself
+func relatedWhere(args *Args, source Quote, params BrowseParams) string {
+	// BM25 similarity to the source quote's indexed text
+	similarParts := []string{fmt.Sprintf(
+		"paradedb.boost(%s, paradedb.more_like_this(document_id => %s, min_term_frequency => 1, min_doc_frequency => 1))",
+		formatBoost(relatedTextBoost), args.Add(source.ID))}
+
+	// Each shared tag adds to the score. Tags and the category are matched
+	// whole, as the query parser does, so "self help" does not relate every
+	// quote tagged "help".
+	for _, tag := range source.Tags {
+		if clause, ok := buildClause("tags", tag, false); ok {
+			similarParts = append(similarParts, fmt.Sprintf("paradedb.boost(%s, %s)",
+				formatBoost(relatedTagBoost), clause.toSQL("tags", args.Add(clause.arg()))))
+		}
+	}
+
+	// Same category
+	if source.Category != nil {
+		if clause, ok := buildClause("category", *source.Category, false); ok {
+			similarParts = append(similarParts, fmt.Sprintf("paradedb.boost(%s, %s)",
+				formatBoost(relatedCategoryBoost), clause.toSQL("category", args.Add(clause.arg()))))
+		}
+	}
+
+	similar := fmt.Sprintf("quotes @@@ paradedb.with_index('quotes_search_idx', paradedb.boolean(should => ARRAY[%s]))",
+		strings.Join(similarParts, ","))
+
+	return NewFilter(params).Where(args, similar, fmt.Sprintf("id <> %s", args.Add(source.ID)))
+}
+
+func (sq *SearchQueries) getSourceQuote(ctx context.Context, id int) (Quote, error) {
+	sql := `
+		SELECT id, quote, author, category, tags
+		FROM quotes
+		WHERE id = $1
+	`
+
+	var q Quote
+	err := sq.db.QueryRow(ctx, sql, id).Scan(&q.ID, &q.Quote, &q.Author, &q.Category, &q.Tags)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Quote{}, ErrQuoteNotFound
+	}
+	return q, err
+}
@@ -0,0 +1,35 @@
+package queries
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRelatedWhere(t *testing.T) {
+	category := "Self Help"
+	source := Quote{ID: 9, Tags: []string{"life", "self help", " "}, Category: &category}
+
+	args := &Args{}
+	sql := relatedWhere(args, source, BrowseParams{})
+
+	for _, want := range []string{
+		"paradedb.more_like_this(document_id => $1,",
+		"paradedb.boost(2, paradedb.term('tags', $2::text))",
+		"paradedb.boost(2, paradedb.phrase('tags', $3::text[]))",
+		"paradedb.boost(1.5, paradedb.phrase('category', $4::text[]))",
+		"id <> $5",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("related WHERE clause is missing %q:\n%s", want, sql)
+		}
+	}
+	if strings.Contains(sql, "paradedb.match('tags'") {
+		t.Errorf("tags are matched word by word:\n%s", sql)
+	}
+
+	want := []interface{}{9, "life", []string{"self", "help"}, []string{"self", "help"}, 9}
+	if values := args.Values(); !reflect.DeepEqual(values, want) {
+		t.Errorf("args = %v, want %v", values, want)
+	}
+}
@@ -3,19 +3,22 @@ package queries
 import (
 	"context"
 	"fmt"
-	"strings"
-	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type SearchQueries struct {
-	db *pgxpool.Pool
+	db       *pgxpool.Pool
+	config   SearchConfig
+	spelling *SpellingIndex
+	embedder Embedder
 }
 
-func NewSearchQueries(db *pgxpool.Pool) *SearchQueries {
-	return &SearchQueries{db: db}
+// NewSearchQueries creates search queries. spelling and embedder may be nil,
+// which disables suggestions and semantic search.
+func NewSearchQueries(db *pgxpool.Pool, config SearchConfig, spelling *SpellingIndex, embedder Embedder) *SearchQueries {
+	return &SearchQueries{db: db, config: config, spelling: spelling, embedder: embedder}
 }
 
 func (sq *SearchQueries) BuildStatement(query string) (pgx.Rows, error) {
@@ -32,86 +35,90 @@ func (sq *SearchQueries) BuildStatement(query string) (pgx.Rows, error) {
 	return sq.db.Query(context.Background(), sql, query)
 }
 
-func (sq *SearchQueries) BuildStatementWithFilters(query string, params BrowseParams) (pgx.Rows, error) {
-	// Build BM25 boolean query parts
-	var booleanParts []string
-	var args []interface{}
-	argIndex := 1
-
-	// Main search query
-	booleanParts = append(booleanParts, fmt.Sprintf("paradedb.match('quote', $%d)", argIndex))
-	args = append(args, query)
-	argIndex++
-
-	// Add BM25-indexed filters to boolean query
-	for _, category := range params.Categories {
-		booleanParts = append(booleanParts, fmt.Sprintf("paradedb.term('category', $%d)", argIndex))
-		args = append(args, category)
-		argIndex++
+// BuildStatementWithFilters selects a page of matches in the requested
+// ranking
+func (sq *SearchQueries) BuildStatementWithFilters(ctx context.Context, query *ParsedQuery, params BrowseParams) (pgx.Rows, error) {
+	sql, args, err := sq.pageSQL(query, params)
+	if err != nil {
+		return nil, err
 	}
+	return sq.db.Query(ctx, sql, args.Values()...)
+}
 
-	// Build WHERE clause parts for non-BM25 fields
-	var whereClauses []string
-
-	// Tags filter (exact array matching)
-	if len(params.Tags) > 0 {
-		placeholders := make([]string, len(params.Tags))
-		for i, tag := range params.Tags {
-			placeholders[i] = fmt.Sprintf("$%d", argIndex)
-			args = append(args, tag)
-			argIndex++
+// pageSQL renders the page query. A cursor seeks past the previous page and
+// fetches one extra row to detect a next page; otherwise the page is selected
+// by offset.
+func (sq *SearchQueries) pageSQL(query *ParsedQuery, params BrowseParams) (string, *Args, error) {
+	args := &Args{}
+	ranking := resolveSearchRanking(params.Sort, params.Order)
+	// Debug output reports the blend components whatever the sort
+	blended := ranking.sort == SortBlended || params.Debug
+	ranked := buildRankedSearch(args, sq.filter(query, params).Where(args), sq.config.Ranking, blended)
+
+	seek := ""
+	limit := params.Limit
+	offset := (params.Page - 1) * params.Limit
+	if params.Cursor != "" {
+		condition, err := ranking.seek(args, params.Cursor)
+		if err != nil {
+			return "", nil, ErrInvalidCursor
 		}
-		whereClauses = append(whereClauses, fmt.Sprintf("tags @> ARRAY[%s]", strings.Join(placeholders, ",")))
+		seek = "WHERE " + condition
+		limit = params.Limit + 1
+		offset = 0
 	}
 
-	// Popularity range filters
-	if params.PopularityMin != nil {
-		whereClauses = append(whereClauses, fmt.Sprintf("popularity >= $%d", argIndex))
-		args = append(args, *params.PopularityMin)
-		argIndex++
-	}
-	if params.PopularityMax != nil {
-		whereClauses = append(whereClauses, fmt.Sprintf("popularity <= $%d", argIndex))
-		args = append(args, *params.PopularityMax)
-		argIndex++
-	}
+	sql := fmt.Sprintf(`
+		SELECT %s
+		FROM (%s) ranked
+		%s
+		%s
+		LIMIT %s OFFSET %s
+	`, rankedSearchColumns, ranked, seek, ranking.orderBy(), args.Add(limit), args.Add(offset))
+
+	return sql, args, nil
+}
+
+// filter combines the parsed query with the request's filters
+func (sq *SearchQueries) filter(query *ParsedQuery, params BrowseParams) Filter {
+	filter := NewFilter(params)
+	filter.Query = query
+	filter.QueryOptions = sq.queryOptions(params)
+	return filter
+}
 
-	// Date range filters
-	if params.DateFrom != nil {
-		whereClauses = append(whereClauses, fmt.Sprintf("created_at >= $%d", argIndex))
-		args = append(args, *params.DateFrom)
-		argIndex++
+// Search runs a filtered search. When fuzzy mode is automatic and the exact
+// query matches nothing, it retries with typo-tolerant matching. Semantic and
+// hybrid searches rank by embeddings instead.
+func (sq *SearchQueries) Search(ctx context.Context, query *ParsedQuery, params BrowseParams) (BrowseResponse, error) {
+	if isVectorMode(params.Mode) {
+		return sq.searchVector(ctx, query, params)
 	}
-	if params.DateTo != nil {
-		whereClauses = append(whereClauses, fmt.Sprintf("created_at <= $%d", argIndex))
-		args = append(args, *params.DateTo)
-		argIndex++
+
+	response, err := sq.searchOnce(ctx, query, params)
+	if err != nil || params.Fuzzy != nil || response.Pagination.TotalCount > 0 || !query.HasFuzzyTerms() {
+		return response, err
 	}
 
-	// Calculate OFFSET
-	offset := (params.Page - 1) * params.Limit
+	fuzzy := MaxFuzzyDistance
+	params.Fuzzy = &fuzzy
+	return sq.searchOnce(ctx, query, params)
+}
 
-	// Build the complete SQL query
-	whereClause := ""
-	if len(whereClauses) > 0 {
-		whereClause = " AND " + strings.Join(whereClauses, " AND ")
+// searchOnce runs one search, in a single statement when configured and the
+// request is not paged by cursor, blended or debugged
+func (sq *SearchQueries) searchOnce(ctx context.Context, query *ParsedQuery, params BrowseParams) (BrowseResponse, error) {
+	if sq.config.SingleQuery && params.Cursor == "" && sq.singleRankable(params) {
+		return sq.searchSingle(ctx, query, params)
 	}
 
-	sql := fmt.Sprintf(`
-		SELECT id, quote, author, category, tags, popularity, created_at,
-		       paradedb.score(id) as relevance,
-		       paradedb.snippet(quote) as highlighted_quote
-		FROM quotes 
-		WHERE quotes @@@ paradedb.with_index('quotes_search_idx', 
-			paradedb.boolean(must => ARRAY[%s])
-		)%s
-		ORDER BY paradedb.score(id) DESC
-		LIMIT $%d OFFSET $%d
-	`, strings.Join(booleanParts, ","), whereClause, argIndex, argIndex+1)
-
-	args = append(args, params.Limit, offset)
+	rows, err := sq.BuildStatementWithFilters(ctx, query, params)
+	if err != nil {
+		return BrowseResponse{}, err
+	}
+	defer rows.Close()
 
-	return sq.db.Query(context.Background(), sql, args...)
+	return sq.BuildResponseWithFilters(ctx, rows, query, params)
 }
 
 func (sq *SearchQueries) BuildResponse(rows pgx.Rows, query string) (SearchResponse, error) {
@@ -141,146 +148,86 @@ func (sq *SearchQueries) BuildResponse(rows pgx.Rows, query string) (SearchRespo
 	}, nil
 }
 
-func (sq *SearchQueries) BuildResponseWithFilters(rows pgx.Rows, query string, params BrowseParams) (BrowseResponse, error) {
-	var quotes []Quote
-	
-	// Parse quotes
+func (sq *SearchQueries) BuildResponseWithFilters(ctx context.Context, rows pgx.Rows, query *ParsedQuery, params BrowseParams) (BrowseResponse, error) {
+	// Count and facets run while the page is read
+	aggregates := startAggregates(ctx, sq.db, sq.filter(query, params), params, sq.config.FacetTimeout, countQuotes)
+
+	var ranked []rankedRow
 	for rows.Next() {
-		var q Quote
-		var tagsArray []string
-		var createdAt interface{}
-		
-		var relevance *float64
-		var highlightedQuote *string
-		err := rows.Scan(&q.ID, &q.Quote, &q.Author, &q.Category, &tagsArray, &q.Popularity, &createdAt, &relevance, &highlightedQuote)
+		row, err := scanRankedRow(rows)
 		if err != nil {
 			return BrowseResponse{}, err
 		}
-		
-		q.Tags = tagsArray
-		// Handle created_at conversion if needed
-		if createdAt != nil {
-			if t, ok := createdAt.(time.Time); ok {
-				createdAtStr := t.Format(time.RFC3339)
-				q.CreatedAt = &createdAtStr
-			}
-		}
-		// Handle relevance and highlighted quote
-		if relevance != nil {
-			q.Relevance = *relevance
-		}
-		if highlightedQuote != nil {
-			q.HighlightedQuote = highlightedQuote
-		}
-		quotes = append(quotes, q)
+		ranked = append(ranked, row)
 	}
 
 	if err := rows.Err(); err != nil {
 		return BrowseResponse{}, err
 	}
 
-	// Get total count for search with filters
-	totalCount, err := sq.getTotalCountWithFilters(query, params)
+	// Get total count and facets for search with filters
+	result, err := aggregates()
 	if err != nil {
 		return BrowseResponse{}, err
 	}
+	totalCount := result.totalCount
 
 	// Build pagination
 	pagination := sq.buildPagination(params.Page, params.Limit, totalCount)
+	if params.Cursor != "" {
+		pagination = sq.buildCursorPagination(params.Limit, totalCount, len(ranked) > params.Limit)
+		if len(ranked) > params.Limit {
+			ranked = ranked[:params.Limit]
+		}
+	}
+	if pagination.HasNext && len(ranked) > 0 {
+		pagination.NextCursor = resolveSearchRanking(params.Sort, params.Order).cursor(ranked[len(ranked)-1])
+	}
+
+	var quotes []Quote
+	for _, row := range ranked {
+		q := row.quote
+		if params.Debug {
+			components := row.components
+			q.ScoreComponents = &components
+		}
+		quotes = append(quotes, q)
+	}
 
 	// Build active filters
 	activeFilters := sq.buildActiveFilters(params)
 
 	response := BrowseResponse{
-		Quotes:        quotes,
-		Pagination:    pagination,
-		ActiveFilters: activeFilters,
-	}
-
-	// Add facets if requested
-	if params.IncludeFacets {
-		facets, err := sq.buildFacetsWithSearch(query, params)
-		if err != nil {
-			return BrowseResponse{}, err
-		}
-		response.Facets = facets
+		Quotes:         quotes,
+		Pagination:     pagination,
+		ActiveFilters:  activeFilters,
+		Facets:         result.facets,
+		FacetsTimedOut: result.facetsTimedOut,
 	}
+	sq.addSuggestions(&response, query, params)
 
 	return response, nil
 }
 
-func (sq *SearchQueries) getTotalCountWithFilters(query string, params BrowseParams) (int, error) {
-	// Build same query as search but with COUNT
-	var booleanParts []string
-	var args []interface{}
-	argIndex := 1
-
-	// Main search query
-	booleanParts = append(booleanParts, fmt.Sprintf("paradedb.match('quote', $%d)", argIndex))
-	args = append(args, query)
-	argIndex++
-
-	// Add BM25-indexed filters to boolean query
-	for _, category := range params.Categories {
-		booleanParts = append(booleanParts, fmt.Sprintf("paradedb.term('category', $%d)", argIndex))
-		args = append(args, category)
-		argIndex++
-	}
-
-	// Build WHERE clause parts for non-BM25 fields
-	var whereClauses []string
-
-	// Tags filter (exact array matching)
-	if len(params.Tags) > 0 {
-		placeholders := make([]string, len(params.Tags))
-		for i, tag := range params.Tags {
-			placeholders[i] = fmt.Sprintf("$%d", argIndex)
-			args = append(args, tag)
-			argIndex++
-		}
-		whereClauses = append(whereClauses, fmt.Sprintf("tags @> ARRAY[%s]", strings.Join(placeholders, ",")))
-	}
-
-	// Popularity range filters
-	if params.PopularityMin != nil {
-		whereClauses = append(whereClauses, fmt.Sprintf("popularity >= $%d", argIndex))
-		args = append(args, *params.PopularityMin)
-		argIndex++
-	}
-	if params.PopularityMax != nil {
-		whereClauses = append(whereClauses, fmt.Sprintf("popularity <= $%d", argIndex))
-		args = append(args, *params.PopularityMax)
-		argIndex++
+// addSuggestions flags fuzzy results and offers "did you mean" corrections
+// when the exact query barely matched
+func (sq *SearchQueries) addSuggestions(response *BrowseResponse, query *ParsedQuery, params BrowseParams) {
+	response.FuzzyApplied = sq.queryOptions(params).FuzzyDistance > 0 && query.HasFuzzyTerms()
+	if sq.spelling != nil && (response.FuzzyApplied || response.Pagination.TotalCount < sq.config.SuggestionThreshold) {
+		response.Suggestions = sq.spelling.Suggest(query, 3)
 	}
+}
 
-	// Date range filters
-	if params.DateFrom != nil {
-		whereClauses = append(whereClauses, fmt.Sprintf("created_at >= $%d", argIndex))
-		args = append(args, *params.DateFrom)
-		argIndex++
-	}
-	if params.DateTo != nil {
-		whereClauses = append(whereClauses, fmt.Sprintf("created_at <= $%d", argIndex))
-		args = append(args, *params.DateTo)
-		argIndex++
+// queryOptions resolves how the parsed query is rendered for this request
+func (sq *SearchQueries) queryOptions(params BrowseParams) QueryOptions {
+	options := QueryOptions{Boosts: sq.config.Boosts}
+	if params.Boosts != nil {
+		options.Boosts = *params.Boosts
 	}
-
-	whereClause := ""
-	if len(whereClauses) > 0 {
-		whereClause = " AND " + strings.Join(whereClauses, " AND ")
+	if params.Fuzzy != nil {
+		options.FuzzyDistance = *params.Fuzzy
 	}
-
-	sql := fmt.Sprintf(`
-		SELECT COUNT(*) 
-		FROM quotes 
-		WHERE quotes @@@ paradedb.with_index('quotes_search_idx', 
-			paradedb.boolean(must => ARRAY[%s])
-		)%s
-	`, strings.Join(booleanParts, ","), whereClause)
-
-	var count int
-	err := sq.db.QueryRow(context.Background(), sql, args...).Scan(&count)
-	return count, err
+	return options
 }
 
 func (sq *SearchQueries) buildPagination(page, limit, totalCount int) Pagination {
@@ -296,6 +243,17 @@ func (sq *SearchQueries) buildPagination(page, limit, totalCount int) Pagination
 	}
 }
 
+// buildCursorPagination describes a keyset page, which has no page number
+func (sq *SearchQueries) buildCursorPagination(limit, totalCount int, hasNext bool) Pagination {
+	return Pagination{
+		Limit:      limit,
+		TotalPages: (totalCount + limit - 1) / limit,
+		TotalCount: totalCount,
+		HasNext:    hasNext,
+		HasPrev:    true,
+	}
+}
+
 func (sq *SearchQueries) buildActiveFilters(params BrowseParams) ActiveFilters {
 	return ActiveFilters{
 		Categories:    params.Categories,
@@ -306,191 +264,3 @@ func (sq *SearchQueries) buildActiveFilters(params BrowseParams) ActiveFilters {
 		DateTo:        params.DateTo,
 	}
 }
-
-func (sq *SearchQueries) buildFacetsWithSearch(query string, params BrowseParams) (*Facets, error) {
-	// For search with filters, we'll generate facets based on the search results
-	// This is a simplified version - you could make this more sophisticated
-	facets := &Facets{}
-
-	// Get category facets for search results
-	categoryFacets, err := sq.getCategoryFacetsWithSearch(query, params)
-	if err != nil {
-		return nil, err
-	}
-	facets.Categories = categoryFacets
-
-	// Get tag facets for search results
-	tagFacets, err := sq.getTagFacetsWithSearch(query, params)
-	if err != nil {
-		return nil, err
-	}
-	facets.Tags = tagFacets
-
-	// Get popularity range for search results
-	popularityRange, err := sq.getPopularityRangeWithSearch(query, params)
-	if err != nil {
-		return nil, err
-	}
-	facets.PopularityRange = popularityRange
-
-	return facets, nil
-}
-
-func (sq *SearchQueries) getCategoryFacetsWithSearch(query string, params BrowseParams) ([]FacetItem, error) {
-	// Build facet query without category filter to show all categories in search results
-	facetParams := params
-	facetParams.Categories = nil
-	
-	var booleanParts []string
-	var args []interface{}
-	argIndex := 1
-
-	// Main search query
-	booleanParts = append(booleanParts, fmt.Sprintf("paradedb.match('quote', $%d)", argIndex))
-	args = append(args, query)
-	argIndex++
-
-	// Build WHERE clause for non-category filters
-	var whereClauses []string
-	if len(facetParams.Tags) > 0 {
-		placeholders := make([]string, len(facetParams.Tags))
-		for i, tag := range facetParams.Tags {
-			placeholders[i] = fmt.Sprintf("$%d", argIndex)
-			args = append(args, tag)
-			argIndex++
-		}
-		whereClauses = append(whereClauses, fmt.Sprintf("tags @> ARRAY[%s]", strings.Join(placeholders, ",")))
-	}
-
-	whereClause := ""
-	if len(whereClauses) > 0 {
-		whereClause = " AND " + strings.Join(whereClauses, " AND ")
-	}
-
-	whereClause += " AND category IS NOT NULL"
-
-	sql := fmt.Sprintf(`
-		SELECT category, COUNT(*) as count
-		FROM quotes
-		WHERE quotes @@@ paradedb.with_index('quotes_search_idx', 
-			paradedb.boolean(must => ARRAY[%s])
-		)%s
-		GROUP BY category
-		ORDER BY count DESC
-		LIMIT $%d
-	`, strings.Join(booleanParts, ","), whereClause, argIndex)
-
-	args = append(args, params.FacetLimit)
-
-	rows, err := sq.db.Query(context.Background(), sql, args...)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var facets []FacetItem
-	for rows.Next() {
-		var item FacetItem
-		err := rows.Scan(&item.Value, &item.Count)
-		if err != nil {
-			return nil, err
-		}
-		facets = append(facets, item)
-	}
-
-	return facets, rows.Err()
-}
-
-func (sq *SearchQueries) getTagFacetsWithSearch(query string, params BrowseParams) ([]FacetItem, error) {
-	// Similar to category facets but for tags
-	facetParams := params
-	facetParams.Tags = nil
-
-	var booleanParts []string
-	var args []interface{}
-	argIndex := 1
-
-	// Main search query
-	booleanParts = append(booleanParts, fmt.Sprintf("paradedb.match('quote', $%d)", argIndex))
-	args = append(args, query)
-	argIndex++
-
-	// Add category filters if any
-	for _, category := range facetParams.Categories {
-		booleanParts = append(booleanParts, fmt.Sprintf("paradedb.term('category', $%d)", argIndex))
-		args = append(args, category)
-		argIndex++
-	}
-
-	sql := fmt.Sprintf(`
-		SELECT unnest(tags) as tag, COUNT(*) as count
-		FROM quotes
-		WHERE quotes @@@ paradedb.with_index('quotes_search_idx', 
-			paradedb.boolean(must => ARRAY[%s])
-		)
-		GROUP BY tag
-		ORDER BY count DESC
-		LIMIT $%d
-	`, strings.Join(booleanParts, ","), argIndex)
-
-	args = append(args, params.FacetLimit)
-
-	rows, err := sq.db.Query(context.Background(), sql, args...)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var facets []FacetItem
-	for rows.Next() {
-		var item FacetItem
-		err := rows.Scan(&item.Value, &item.Count)
-		if err != nil {
-			return nil, err
-		}
-		facets = append(facets, item)
-	}
-
-	return facets, rows.Err()
-}
-
-func (sq *SearchQueries) getPopularityRangeWithSearch(query string, params BrowseParams) (*PopularityRange, error) {
-	var booleanParts []string
-	var args []interface{}
-	argIndex := 1
-
-	// Main search query
-	booleanParts = append(booleanParts, fmt.Sprintf("paradedb.match('quote', $%d)", argIndex))
-	args = append(args, query)
-	argIndex++
-
-	// Add category filters if any
-	for _, category := range params.Categories {
-		booleanParts = append(booleanParts, fmt.Sprintf("paradedb.term('category', $%d)", argIndex))
-		args = append(args, category)
-		argIndex++
-	}
-
-	sql := fmt.Sprintf(`
-		SELECT MIN(popularity), MAX(popularity)
-		FROM quotes
-		WHERE quotes @@@ paradedb.with_index('quotes_search_idx', 
-			paradedb.boolean(must => ARRAY[%s])
-		) AND popularity IS NOT NULL
-	`, strings.Join(booleanParts, ","))
-
-	var min, max *float64
-	err := sq.db.QueryRow(context.Background(), sql, args...).Scan(&min, &max)
-	if err != nil {
-		return nil, err
-	}
-
-	if min == nil || max == nil {
-		return nil, nil
-	}
-
-	return &PopularityRange{
-		Min: *min,
-		Max: *max,
-	}, nil
-}
\ No newline at end of file
@@ -0,0 +1,228 @@
+package queries
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"golang.org/x/sync/errgroup"
+)
+
+// Search modes
+const (
+	ModeKeyword  = "keyword"
+	ModeSemantic = "semantic"
+	ModeHybrid   = "hybrid"
+)
+
+// isVectorMode reports whether a search mode ranks by embeddings
+func isVectorMode(mode string) bool {
+	return mode == ModeSemantic || mode == ModeHybrid
+}
+
+// semanticCandidatesSQL ranks the quotes nearest to the query vector
+const semanticCandidatesSQL = `
+		SELECT id, ROW_NUMBER() OVER (ORDER BY distance, id) AS rank, distance
+		FROM (
+			SELECT id, embedding <=> %[1]s::vector AS distance
+			FROM quotes
+			%[2]s
+			ORDER BY embedding <=> %[1]s::vector
+			LIMIT %[3]s
+		) nearest`
+
+// keywordCandidatesSQL ranks the best BM25 matches
+const keywordCandidatesSQL = `
+		SELECT id, ROW_NUMBER() OVER (ORDER BY score DESC, id DESC) AS rank
+		FROM (
+			SELECT id, paradedb.score(id) AS score
+			FROM quotes
+			%[1]s
+			ORDER BY paradedb.score(id) DESC, id DESC
+			LIMIT %[2]s
+		) matched`
+
+// maxEfSearch is the largest hnsw.ef_search pgvector accepts
+const maxEfSearch = 1000
+
+// vectorSettingsSQL widens the HNSW search for the rest of a transaction. An
+// index scan returns at most hnsw.ef_search rows, 40 by default, and fewer
+// once filters drop some of them, so ef_search is raised to the candidate
+// count and, where pgvector supports it, iterative scans keep reading until
+// enough rows pass the filters.
+const vectorSettingsSQL = `
+	SELECT set_config('hnsw.ef_search', $1, true),
+	       CASE WHEN current_setting('hnsw.iterative_scan', true) IS NOT NULL
+	            THEN set_config('hnsw.iterative_scan', 'strict_order', true)
+	       END
+`
+
+// widenVectorSearch applies vectorSettingsSQL to tx
+func (sq *SearchQueries) widenVectorSearch(ctx context.Context, tx pgx.Tx) error {
+	efSearch := min(max(sq.config.SemanticCandidates, 40), maxEfSearch)
+	_, err := tx.Exec(ctx, vectorSettingsSQL, strconv.Itoa(efSearch))
+	return err
+}
+
+// noCandidatesSQL stands in for the semantic ranking when the query has no
+// direction to compare
+const noCandidatesSQL = `
+		SELECT NULL::int AS id, NULL::bigint AS rank, NULL::float8 AS distance WHERE false`
+
+// semanticSQL pages the semantic candidates by cosine similarity
+const semanticSQL = `
+	WITH semantic AS (%[1]s
+	)
+	SELECT q.id, q.quote, q.author, q.category, q.tags, q.popularity, q.created_at,
+	       (1 - semantic.distance)::float8 AS relevance,
+	       COUNT(*) OVER () AS total_count
+	FROM semantic
+	JOIN quotes q USING (id)
+	ORDER BY semantic.rank
+	LIMIT %[2]s OFFSET %[3]s
+`
+
+// hybridSQL fuses the keyword and semantic rankings by reciprocal rank fusion:
+// each quote scores the sum of 1 / (k + rank) over the rankings it appears in
+const hybridSQL = `
+	WITH keyword AS (%[1]s
+	),
+	semantic AS (%[2]s
+	),
+	fused AS (
+		SELECT id, SUM(1.0 / (%[3]s + rank))::float8 AS relevance
+		FROM (
+			SELECT id, rank FROM keyword
+			UNION ALL
+			SELECT id, rank FROM semantic
+		) ranks
+		GROUP BY id
+	)
+	SELECT q.id, q.quote, q.author, q.category, q.tags, q.popularity, q.created_at,
+	       fused.relevance,
+	       COUNT(*) OVER () AS total_count
+	FROM fused
+	JOIN quotes q USING (id)
+	ORDER BY fused.relevance DESC, q.id DESC
+	LIMIT %[4]s OFFSET %[5]s
+`
+
+// vectorSQL renders the page query of a semantic or hybrid search. Both
+// rank a bounded candidate list, which is what the total count reports.
+func (sq *SearchQueries) vectorSQL(query *ParsedQuery, params BrowseParams, vector []float32) (string, *Args) {
+	args := &Args{}
+	candidates := args.Add(sq.config.SemanticCandidates)
+
+	semantic := noCandidatesSQL
+	if !isZeroVector(vector) {
+		semantic = fmt.Sprintf(semanticCandidatesSQL,
+			args.Add(vectorLiteral(vector)),
+			sq.vectorFilter(query, params).Where(args, "embedding IS NOT NULL", "embedding_model = "+args.Add(sq.embedder.Model())),
+			candidates)
+	}
+
+	offset := (params.Page - 1) * params.Limit
+	if params.Mode == ModeSemantic {
+		return fmt.Sprintf(semanticSQL, semantic, args.Add(params.Limit), args.Add(offset)), args
+	}
+
+	keyword := fmt.Sprintf(keywordCandidatesSQL, sq.filter(query, params).Where(args), candidates)
+	return fmt.Sprintf(hybridSQL, keyword, semantic, args.Add(sq.config.RRFK),
+		args.Add(params.Limit), args.Add(offset)), args
+}
+
+// vectorFilter combines the request's filters with the query's field scopes
+// and exclusions, which semantic candidates must honour like keyword matches
+func (sq *SearchQueries) vectorFilter(query *ParsedQuery, params BrowseParams) Filter {
+	filter := NewFilter(params)
+	filter.Query = query.Constraints()
+	filter.QueryOptions = QueryOptions{Boosts: sq.queryOptions(params).Boosts}
+	return filter
+}
+
+// embedQuery embeds the text of a parsed query
+func (sq *SearchQueries) embedQuery(ctx context.Context, query *ParsedQuery) ([]float32, error) {
+	if sq.embedder == nil {
+		return nil, ErrSemanticUnavailable
+	}
+	vectors, err := sq.embedder.Embed(ctx, []string{query.Text()})
+	if err != nil {
+		return nil, err
+	}
+	return vectors[0], nil
+}
+
+// searchVector runs a semantic or hybrid search. Results are ranked by
+// similarity or fused rank, so sort is ignored and cursors are not supported.
+// Facets describe the filtered quotes, since semantic matches are not
+// limited to the query's words. The page is read in its own transaction so
+// the HNSW search can be widened to the candidate count.
+func (sq *SearchQueries) searchVector(ctx context.Context, query *ParsedQuery, params BrowseParams) (BrowseResponse, error) {
+	if params.Cursor != "" {
+		return BrowseResponse{}, ErrInvalidCursor
+	}
+	vector, err := sq.embedQuery(ctx, query)
+	if err != nil {
+		return BrowseResponse{}, err
+	}
+	sql, args := sq.vectorSQL(query, params, vector)
+
+	var quotes []Quote
+	totalCount := 0
+	var facets *Facets
+	facetsTimedOut := false
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		tx, err := sq.db.BeginTx(gctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback(gctx)
+
+		if err := sq.widenVectorSearch(gctx, tx); err != nil {
+			return err
+		}
+		rows, err := tx.Query(gctx, sql, args.Values()...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var q Quote
+			var createdAt *time.Time
+			err := rows.Scan(&q.ID, &q.Quote, &q.Author, &q.Category, &q.Tags, &q.Popularity, &createdAt,
+				&q.Relevance, &totalCount)
+			if err != nil {
+				return err
+			}
+			if createdAt != nil {
+				createdAtStr := createdAt.Format(time.RFC3339)
+				q.CreatedAt = &createdAtStr
+			}
+			quotes = append(quotes, q)
+		}
+		return rows.Err()
+	})
+	if params.IncludeFacets {
+		g.Go(func() error {
+			var err error
+			facets, facetsTimedOut, err = buildFacetsWithin(gctx, sq.db, sq.vectorFilter(query, params), params.FacetLimit, sq.config.FacetTimeout)
+			return err
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return BrowseResponse{}, err
+	}
+
+	return BrowseResponse{
+		Quotes:         quotes,
+		Pagination:     sq.buildPagination(params.Page, params.Limit, totalCount),
+		ActiveFilters:  sq.buildActiveFilters(params),
+		Facets:         facets,
+		FacetsTimedOut: facetsTimedOut,
+	}, nil
+}
@@ -0,0 +1,278 @@
+package queries
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// singleStatementSQL returns a result page, its total count and its facets in
+// one round trip. The base CTE applies every filter no facet ignores and
+// flags rows by the facet filters, so each facet can drop its own filter the
+// way buildFacets does. Snippets are taken by the highlights CTE for the
+// page's rows only. The aggregates row is always returned; page columns are
+// NULL when the page is empty.
+const singleStatementSQL = `
+	WITH base AS MATERIALIZED (
+		SELECT id, quote, author, category, tags, popularity, created_at,
+		       %[1]s AS relevance,
+		       %[2]s AS in_categories,
+		       %[3]s AS in_tags,
+		       %[4]s AS in_popularity
+		FROM quotes
+		%[5]s
+	),
+	matched AS (
+		SELECT * FROM base WHERE in_categories AND in_tags AND in_popularity
+	),
+	page AS (
+		SELECT id, quote, author, category, tags, popularity, created_at, relevance,
+		       COUNT(*) OVER () AS total_count,
+		       ROW_NUMBER() OVER (%[6]s) AS position
+		FROM matched
+		ORDER BY position
+		LIMIT %[7]s OFFSET %[8]s
+	),
+	highlights AS (
+		%[10]s
+	),
+	aggregates AS (
+		SELECT %[9]s
+	)
+	SELECT page.id, page.quote, page.author, page.category, page.tags, page.popularity, page.created_at,
+	       page.relevance, highlights.highlighted_quote, page.total_count,
+	       aggregates.category_facets, aggregates.tag_facets,
+	       aggregates.popularity_min, aggregates.popularity_max
+	FROM aggregates
+	LEFT JOIN page ON true
+	LEFT JOIN highlights ON highlights.id = page.id
+	ORDER BY page.position
+`
+
+// singleHighlightsSQL highlights the page's rows in a BM25 scan of their own,
+// which is where paradedb.snippet is computed
+const singleHighlightsSQL = `SELECT id, paradedb.snippet(quote) AS highlighted_quote
+		FROM quotes
+		%s`
+
+// singleNoHighlightsSQL stands in for the highlights of an unsearched page
+const singleNoHighlightsSQL = `SELECT NULL::int AS id, NULL::text AS highlighted_quote WHERE false`
+
+// singleFacetsSQL aggregates the facets over base, each ignoring its own filter
+const singleFacetsSQL = `
+		(SELECT COALESCE(json_agg(json_build_object('value', category, 'count', count) ORDER BY count DESC), '[]'::json)
+		 FROM (SELECT category, COUNT(*) AS count
+		       FROM base
+		       WHERE in_tags AND in_popularity AND category IS NOT NULL
+		       GROUP BY category
+		       ORDER BY count DESC
+		       LIMIT %[1]s) c) AS category_facets,
+		(SELECT COALESCE(json_agg(json_build_object('value', tag, 'count', count) ORDER BY count DESC), '[]'::json)
+		 FROM (SELECT tag, COUNT(*) AS count
+		       FROM base, unnest(tags) AS tag
+		       WHERE in_categories AND in_popularity
+		       GROUP BY tag
+		       ORDER BY count DESC
+		       LIMIT %[1]s) t) AS tag_facets,
+		(SELECT MIN(popularity)::float8 FROM base WHERE in_categories AND in_tags) AS popularity_min,
+		(SELECT MAX(popularity)::float8 FROM base WHERE in_categories AND in_tags) AS popularity_max`
+
+const singleNoFacetsSQL = `NULL::json AS category_facets, NULL::json AS tag_facets,
+		       NULL::float8 AS popularity_min, NULL::float8 AS popularity_max`
+
+// singleResult is a page read back from singleStatementSQL
+type singleResult struct {
+	quotes     []Quote
+	createdAts []*time.Time
+	// totalCount is nil when the page was empty, since the window count
+	// then has no row to ride on
+	totalCount *int
+	facets     *Facets
+}
+
+// buildSingleStatement renders singleStatementSQL for filter. relevance is
+// the expression of the relevance column; orderBy is a full ORDER BY clause
+// over the base columns. highlight takes snippets of the page, for searches.
+func buildSingleStatement(args *Args, filter Filter, params BrowseParams, relevance, orderBy string, highlight bool) string {
+	common := filter.Without(FacetCategory).Without(FacetTags).Without(FacetPopularity)
+	whereClause := common.Where(args)
+	inCategories := filter.Only(FacetCategory).Predicate(args)
+	inTags := filter.Only(FacetTags).Predicate(args)
+	inPopularity := filter.Only(FacetPopularity).Predicate(args)
+
+	aggregates := singleNoFacetsSQL
+	if params.IncludeFacets {
+		aggregates = fmt.Sprintf(singleFacetsSQL, args.Add(params.FacetLimit))
+	}
+
+	highlights := singleNoHighlightsSQL
+	if highlight {
+		highlights = fmt.Sprintf(singleHighlightsSQL, common.Where(args, "id IN (SELECT id FROM page)"))
+	}
+
+	offset := (params.Page - 1) * params.Limit
+
+	return fmt.Sprintf(singleStatementSQL,
+		relevance, inCategories, inTags, inPopularity, whereClause,
+		orderBy, args.Add(params.Limit), args.Add(offset), aggregates, highlights)
+}
+
+// querySingle runs a statement from buildSingleStatement and reads it back
+func querySingle(ctx context.Context, db *pgxpool.Pool, sql string, args *Args, params BrowseParams) (singleResult, error) {
+	rows, err := db.Query(ctx, sql, args.Values()...)
+	if err != nil {
+		return singleResult{}, err
+	}
+	defer rows.Close()
+
+	var result singleResult
+	if params.IncludeFacets {
+		result.facets = &Facets{}
+	}
+
+	for rows.Next() {
+		var id *int
+		var quote, author, highlightedQuote *string
+		var q Quote
+		var createdAt *time.Time
+		var relevance *float64
+		var totalCount *int
+		var categories, tags []FacetItem
+		var popularityMin, popularityMax *float64
+
+		err := rows.Scan(&id, &quote, &author, &q.Category, &q.Tags, &q.Popularity, &createdAt,
+			&relevance, &highlightedQuote, &totalCount,
+			&categories, &tags, &popularityMin, &popularityMax)
+		if err != nil {
+			return singleResult{}, err
+		}
+
+		// Every row carries the same aggregates
+		result.totalCount = totalCount
+		if result.facets != nil {
+			result.facets.Categories = categories
+			result.facets.Tags = tags
+			result.facets.PopularityRange = nil
+			if popularityMin != nil && popularityMax != nil {
+				result.facets.PopularityRange = &PopularityRange{Min: *popularityMin, Max: *popularityMax}
+			}
+		}
+
+		// An empty page still returns the aggregates row
+		if id == nil {
+			continue
+		}
+
+		q.ID, q.Quote, q.Author = *id, *quote, *author
+		q.HighlightedQuote = highlightedQuote
+		if relevance != nil {
+			q.Relevance = *relevance
+		}
+		if createdAt != nil {
+			createdAtStr := createdAt.Format(time.RFC3339)
+			q.CreatedAt = &createdAtStr
+		}
+		result.quotes = append(result.quotes, q)
+		result.createdAts = append(result.createdAts, createdAt)
+	}
+
+	return result, rows.Err()
+}
+
+// resolveTotal returns the total count of a single-statement page. An empty
+// page past the first has no window count, so the total is counted separately.
+func (r singleResult) resolveTotal(ctx context.Context, db *pgxpool.Pool, filter Filter, params BrowseParams) (int, error) {
+	if r.totalCount != nil {
+		return *r.totalCount, nil
+	}
+	if params.Page <= 1 {
+		return 0, nil
+	}
+	return countQuotes(ctx, db, filter)
+}
+
+// browseSingle is the single-round-trip form of BuildStatement and BuildResponse
+func (bq *BrowseQueries) browseSingle(ctx context.Context, params BrowseParams) (BrowseResponse, error) {
+	args := &Args{}
+	filter := NewFilter(params)
+	sql := buildSingleStatement(args, filter, params, "NULL::float8",
+		bq.buildOrderBy(params.Sort, params.Order, params.Seed), false)
+
+	result, err := querySingle(ctx, bq.db, sql, args, params)
+	if err != nil {
+		return BrowseResponse{}, err
+	}
+	totalCount, err := result.resolveTotal(ctx, bq.db, filter, params)
+	if err != nil {
+		return BrowseResponse{}, err
+	}
+
+	pagination := bq.buildPagination(params.Page, params.Limit, totalCount)
+	if pagination.HasNext && len(result.quotes) > 0 {
+		last := len(result.quotes) - 1
+		pagination.NextCursor = bq.nextCursor(params, result.quotes[last], result.createdAts[last])
+	}
+
+	return BrowseResponse{
+		Quotes:        result.quotes,
+		Pagination:    pagination,
+		ActiveFilters: bq.buildActiveFilters(params),
+		Facets:        result.facets,
+	}, nil
+}
+
+// searchSingle is the single-round-trip form of BuildStatementWithFilters and
+// BuildResponseWithFilters
+func (sq *SearchQueries) searchSingle(ctx context.Context, query *ParsedQuery, params BrowseParams) (BrowseResponse, error) {
+	filter := sq.filter(query, params)
+	ranking := resolveSearchRanking(params.Sort, params.Order)
+	sql, args := sq.singleSQL(query, params)
+
+	result, err := querySingle(ctx, sq.db, sql, args, params)
+	if err != nil {
+		return BrowseResponse{}, err
+	}
+	totalCount, err := result.resolveTotal(ctx, sq.db, filter, params)
+	if err != nil {
+		return BrowseResponse{}, err
+	}
+
+	pagination := sq.buildPagination(params.Page, params.Limit, totalCount)
+	if pagination.HasNext && len(result.quotes) > 0 {
+		last := len(result.quotes) - 1
+		q := result.quotes[last]
+		pagination.NextCursor = ranking.cursor(rankedRow{
+			quote:      q,
+			createdAt:  result.createdAts[last],
+			components: ScoreComponents{BM25: q.Relevance},
+		})
+	}
+
+	response := BrowseResponse{
+		Quotes:        result.quotes,
+		Pagination:    pagination,
+		ActiveFilters: sq.buildActiveFilters(params),
+		Facets:        result.facets,
+	}
+	sq.addSuggestions(&response, query, params)
+
+	return response, nil
+}
+
+// singleSQL renders the single statement searchSingle runs
+func (sq *SearchQueries) singleSQL(query *ParsedQuery, params BrowseParams) (string, *Args) {
+	args := &Args{}
+	sql := buildSingleStatement(args, sq.filter(query, params), params, "paradedb.score(id)::float8",
+		resolveSearchRanking(params.Sort, params.Order).orderBy(), true)
+	return sql, args
+}
+
+// singleRankable reports whether searchSingle can rank the request. The
+// blended ranking normalizes over every match and debug output needs its
+// components, so both use the multi-query path.
+func (sq *SearchQueries) singleRankable(params BrowseParams) bool {
+	ranking := resolveSearchRanking(params.Sort, params.Order)
+	return ranking.sort != SortBlended && !params.Debug
+}
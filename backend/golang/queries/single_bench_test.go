@@ -0,0 +1,86 @@
+package queries
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Compare the multi-query and single-statement response paths against a real
+// database:
+//
+//	DATABASE_URL=... go test ./queries -run '^$' -bench Response
+func benchmarkPool(b *testing.B) *pgxpool.Pool {
+	databaseURL := os.Getenv("DATABASE_URL")
+	if databaseURL == "" {
+		b.Skip("DATABASE_URL is not set")
+	}
+	pool, err := pgxpool.New(context.Background(), databaseURL)
+	if err != nil {
+		b.Fatalf("connect: %v", err)
+	}
+	b.Cleanup(pool.Close)
+	return pool
+}
+
+func benchmarkParams() BrowseParams {
+	return BrowseParams{
+		Page:          1,
+		Limit:         20,
+		Sort:          "popularity",
+		Order:         "desc",
+		IncludeFacets: true,
+		FacetLimit:    10,
+	}
+}
+
+func BenchmarkBrowseResponse(b *testing.B) {
+	pool := benchmarkPool(b)
+	ctx := context.Background()
+
+	for _, mode := range []struct {
+		name   string
+		single bool
+	}{{"multi-query", false}, {"single-query", true}} {
+		config := DefaultSearchConfig()
+		config.SingleQuery = mode.single
+		bq := NewBrowseQueries(pool, config)
+
+		b.Run(mode.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := bq.Browse(ctx, benchmarkParams()); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkSearchResponse(b *testing.B) {
+	pool := benchmarkPool(b)
+	ctx := context.Background()
+
+	query, err := ParseQuery("life")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for _, mode := range []struct {
+		name   string
+		single bool
+	}{{"multi-query", false}, {"single-query", true}} {
+		config := DefaultSearchConfig()
+		config.SingleQuery = mode.single
+		sq := NewSearchQueries(pool, config, nil, nil)
+
+		b.Run(mode.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := sq.Search(ctx, query, benchmarkParams()); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package queries
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSingleSQLHighlightsPageOnly(t *testing.T) {
+	query, _ := ParseQuery("courage")
+	sq := NewSearchQueries(nil, DefaultSearchConfig(), nil, nil)
+
+	sql, _ := sq.singleSQL(query, BrowseParams{Page: 1, Limit: 20, IncludeFacets: true, FacetLimit: 10})
+	base := sql[:strings.Index(sql, "matched AS")]
+	highlights := sql[strings.Index(sql, "highlights AS"):strings.Index(sql, "aggregates AS")]
+	if strings.Contains(base, "paradedb.snippet") {
+		t.Errorf("every match is highlighted in the base CTE:\n%s", sql)
+	}
+	if !strings.Contains(highlights, "paradedb.snippet(quote)") || !strings.Contains(highlights, "id IN (SELECT id FROM page)") {
+		t.Errorf("highlights do not cover just the page:\n%s", highlights)
+	}
+
+	bq := NewBrowseQueries(nil, DefaultSearchConfig())
+	browse := buildSingleStatement(&Args{}, NewFilter(BrowseParams{}), BrowseParams{Page: 1, Limit: 20}, "NULL::float8", bq.buildOrderBy("popularity", "desc", nil), false)
+	if strings.Contains(browse, "paradedb.snippet") || !strings.Contains(browse, singleNoHighlightsSQL) {
+		t.Errorf("browse highlights:\n%s", browse)
+	}
+}
@@ -0,0 +1,260 @@
+package queries
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// SpellingIndex suggests corrections for search words using the vocabulary
+// of the indexed quote, author, category and tag text. It is held in memory
+// and refreshed periodically.
+type SpellingIndex struct {
+	db *pgxpool.Pool
+
+	mu       sync.RWMutex
+	words    map[string]int   // word -> number of quotes using it
+	byLength map[int][]string // words grouped by rune count
+}
+
+func NewSpellingIndex(db *pgxpool.Pool) *SpellingIndex {
+	return &SpellingIndex{db: db}
+}
+
+// Refresh reloads the vocabulary from the quotes table
+func (si *SpellingIndex) Refresh(ctx context.Context) error {
+	sql := `
+		SELECT word, COUNT(DISTINCT id) as frequency
+		FROM quotes,
+		     unnest(regexp_split_to_array(
+		         lower(quote || ' ' || author || ' ' || COALESCE(category, '') || ' ' || array_to_string(tags, ' ')),
+		         '[^[:alnum:]]+'
+		     )) AS word
+		WHERE length(word) > 2
+		GROUP BY word
+	`
+
+	rows, err := si.db.Query(ctx, sql)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	words := make(map[string]int)
+	byLength := make(map[int][]string)
+	for rows.Next() {
+		var word string
+		var frequency int
+		if err := rows.Scan(&word, &frequency); err != nil {
+			return err
+		}
+		words[word] = frequency
+		n := utf8.RuneCountInString(word)
+		byLength[n] = append(byLength[n], word)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	si.mu.Lock()
+	si.words = words
+	si.byLength = byLength
+	si.mu.Unlock()
+
+	return nil
+}
+
+// Suggest returns up to limit corrected versions of the query. Only words that
+// are not in the vocabulary are replaced; an empty result means nothing to fix.
+func (si *SpellingIndex) Suggest(query *ParsedQuery, limit int) []string {
+	si.mu.RLock()
+	defer si.mu.RUnlock()
+
+	if len(si.words) == 0 || limit <= 0 {
+		return nil
+	}
+
+	// Candidate corrections for each misspelled search word, best first
+	corrections := make(map[string][]string)
+	maxAlternatives := 0
+	for _, clauses := range [][]QueryClause{query.Must, query.Should} {
+		for _, clause := range clauses {
+			if clause.Type != ClauseMatch {
+				continue
+			}
+			for _, token := range clause.Tokens {
+				if _, known := si.words[token]; known {
+					continue
+				}
+				if _, seen := corrections[token]; seen {
+					continue
+				}
+				if candidates := si.candidates(token, limit); len(candidates) > 0 {
+					corrections[token] = candidates
+					maxAlternatives = max(maxAlternatives, len(candidates))
+				}
+			}
+		}
+	}
+
+	// The n-th suggestion uses each word's n-th best correction
+	var suggestions []string
+	seen := make(map[string]bool)
+	for n := 0; n < maxAlternatives && len(suggestions) < limit; n++ {
+		suggestion := rewriteQuery(query.Raw, func(token string) string {
+			candidates, ok := corrections[token]
+			if !ok {
+				return ""
+			}
+			return candidates[min(n, len(candidates)-1)]
+		})
+		if !seen[suggestion] {
+			seen[suggestion] = true
+			suggestions = append(suggestions, suggestion)
+		}
+	}
+
+	return suggestions
+}
+
+type spellingCandidate struct {
+	word      string
+	distance  int
+	frequency int
+}
+
+// candidates finds vocabulary words within the token's fuzzy edit distance,
+// closest and most frequent first. The caller must hold the read lock.
+func (si *SpellingIndex) candidates(token string, limit int) []string {
+	maxDistance := fuzzyDistance(token, MaxFuzzyDistance)
+	if maxDistance == 0 {
+		return nil
+	}
+
+	target := []rune(token)
+	var found []spellingCandidate
+	for n := len(target) - maxDistance; n <= len(target)+maxDistance; n++ {
+		for _, word := range si.byLength[n] {
+			if d := editDistance(target, []rune(word), maxDistance); d <= maxDistance {
+				found = append(found, spellingCandidate{word: word, distance: d, frequency: si.words[word]})
+			}
+		}
+	}
+
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].distance != found[j].distance {
+			return found[i].distance < found[j].distance
+		}
+		if found[i].frequency != found[j].frequency {
+			return found[i].frequency > found[j].frequency
+		}
+		return found[i].word < found[j].word
+	})
+
+	words := make([]string, 0, min(limit, len(found)))
+	for i := 0; i < len(found) && i < limit; i++ {
+		words = append(words, found[i].word)
+	}
+	return words
+}
+
+// rewriteQuery rewrites the words of the clauses Suggest corrects: bare,
+// required and field-scoped words. Exclusions, phrases, field names and
+// operators are kept as typed, even where they share a misspelled word.
+func rewriteQuery(raw string, replace func(token string) string) string {
+	p := &queryParser{input: raw}
+	var b strings.Builder
+	last := 0
+	for {
+		term, ok, err := p.next()
+		if err != nil || !ok {
+			break
+		}
+		if term.operator == '-' {
+			continue
+		}
+		if clause, ok := buildClause(term.field, term.value(raw), term.phrase); !ok || clause.Type != ClauseMatch {
+			continue
+		}
+		b.WriteString(raw[last:term.valueStart])
+		b.WriteString(rewriteTokens(term.value(raw), replace))
+		last = term.valueEnd
+	}
+	b.WriteString(raw[last:])
+
+	return b.String()
+}
+
+// rewriteTokens replaces each alphanumeric run of s for which replace returns
+// a non-empty string, leaving operators, fields and punctuation intact
+func rewriteTokens(s string, replace func(token string) string) string {
+	var b strings.Builder
+	start := -1
+	flush := func(end int) {
+		if start < 0 {
+			return
+		}
+		word := s[start:end]
+		if replacement := replace(strings.ToLower(word)); replacement != "" {
+			b.WriteString(replacement)
+		} else {
+			b.WriteString(word)
+		}
+		start = -1
+	}
+
+	for i, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		flush(i)
+		b.WriteRune(r)
+	}
+	flush(len(s))
+
+	return b.String()
+}
+
+// editDistance is the Damerau-Levenshtein (optimal string alignment) distance
+// between a and b. It returns maxDistance+1 as soon as that bound is exceeded.
+func editDistance(a, b []rune, maxDistance int) int {
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	prevRowMin := 0
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				curr[j] = min(curr[j], prev2[j-2]+1)
+			}
+			rowMin = min(rowMin, curr[j])
+		}
+		// A transposition can reach back two rows, so both must exceed the bound
+		if rowMin > maxDistance && prevRowMin > maxDistance {
+			return maxDistance + 1
+		}
+		prevRowMin = rowMin
+		prev2, prev, curr = prev, curr, prev2
+	}
+
+	return min(prev[len(b)], maxDistance+1)
+}
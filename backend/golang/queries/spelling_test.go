@@ -0,0 +1,109 @@
+package queries
+
+import (
+	"reflect"
+	"testing"
+	"unicode/utf8"
+)
+
+// spellingIndexOf builds an index over a fixed vocabulary of word frequencies
+func spellingIndexOf(words map[string]int) *SpellingIndex {
+	si := &SpellingIndex{words: words, byLength: make(map[int][]string)}
+	for word := range words {
+		n := utf8.RuneCountInString(word)
+		si.byLength[n] = append(si.byLength[n], word)
+	}
+	return si
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b        string
+		maxDistance int
+		want        int
+	}{
+		{"courage", "courage", 2, 0},
+		{"corage", "courage", 2, 1},
+		{"courrage", "courage", 2, 1},
+		{"cowrage", "courage", 2, 1},
+		// A transposition is a single edit
+		{"hpapy", "happy", 2, 1},
+		{"teh", "the", 1, 1},
+		{"cuoarge", "courage", 2, 2},
+		// Past the bound the distance is reported as maxDistance+1
+		{"kitten", "sitting", 2, 3},
+		{"kitten", "sitting", 1, 2},
+		{"wisdom", "courage", 2, 3},
+		{"", "war", 2, 3},
+	}
+
+	for _, tt := range tests {
+		if got := editDistance([]rune(tt.a), []rune(tt.b), tt.maxDistance); got != tt.want {
+			t.Errorf("editDistance(%q, %q, %d) = %d, want %d", tt.a, tt.b, tt.maxDistance, got, tt.want)
+		}
+	}
+}
+
+func TestSpellingCandidates(t *testing.T) {
+	si := spellingIndexOf(map[string]int{"courage": 40, "carriage": 2, "love": 90, "live": 30, "life": 60, "wisdom": 12})
+
+	tests := []struct {
+		token string
+		want  []string
+	}{
+		// Tokens of 3 characters or fewer are never corrected
+		{"lve", nil},
+		// Up to 6 characters allow one edit, closest then most frequent first
+		{"lofe", []string{"love", "life"}},
+		{"wisdon", []string{"wisdom"}},
+		{"lxxe", []string{}},
+		// Longer tokens allow two edits
+		{"curage", []string{"courage"}},
+		{"coruage", []string{"courage"}},
+		{"carrage", []string{"carriage", "courage"}},
+	}
+
+	for _, tt := range tests {
+		if got := si.candidates(tt.token, 5); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("candidates(%q) = %q, want %q", tt.token, got, tt.want)
+		}
+	}
+
+	if got := si.candidates("carrage", 1); !reflect.DeepEqual(got, []string{"carriage"}) {
+		t.Errorf("candidates limited to 1 = %q", got)
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	si := spellingIndexOf(map[string]int{"courage": 40, "never": 20, "give": 25, "war": 8, "churchill": 5, "life": 60, "love": 90, "authors": 3})
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"corage", []string{"courage"}},
+		{"never giev up", []string{"never give up"}},
+		{"+Corage", []string{"+courage"}},
+		{"author:churchil", []string{"author:churchill"}},
+		// Only the bare word is rewritten, not the phrase or exclusion sharing it
+		{`"nevr give up" nevr`, []string{`"nevr give up" never`}},
+		{"-corage corage", []string{"-corage courage"}},
+		{"-corage love", nil},
+		// A field name spelled like a corrected word is left alone
+		{"author author:churchill", []string{"authors author:churchill"}},
+		{"tag:lief courage", nil},
+		// The n-th suggestion takes each word's n-th best correction
+		{"lofe", []string{"love", "life"}},
+		{"courage", nil},
+	}
+
+	for _, tt := range tests {
+		query, err := ParseQuery(tt.query)
+		if err != nil {
+			t.Fatalf("ParseQuery(%q): %v", tt.query, err)
+		}
+		if got := si.Suggest(query, 3); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Suggest(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
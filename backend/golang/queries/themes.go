@@ -0,0 +1,494 @@
+package queries
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrThemeNotFound is returned when no theme has a slug
+var ErrThemeNotFound = errors.New("theme not found")
+
+// ErrDuplicateTheme is returned when a write would violate uq_themes_slug
+var ErrDuplicateTheme = errors.New("theme slug already exists")
+
+// defaultSnapshotLimit is how many results a snapshot freezes unless told otherwise
+const defaultSnapshotLimit = 50
+
+// ThemeParams are the browse parameters a theme runs with. Paging, facets and
+// debug output belong to the request reading the theme, not to the theme.
+type ThemeParams struct {
+	Sort          string        `json:"sort,omitempty"`
+	Order         string        `json:"order,omitempty"`
+	Mode          string        `json:"mode,omitempty"`
+	Categories    []string      `json:"categories,omitempty"`
+	Tags          []string      `json:"tags,omitempty"`
+	PopularityMin *float64      `json:"popularity_min,omitempty"`
+	PopularityMax *float64      `json:"popularity_max,omitempty"`
+	DateFrom      *string       `json:"date_from,omitempty"`
+	DateTo        *string       `json:"date_to,omitempty"`
+	AuthorSlug    string        `json:"author_slug,omitempty"`
+	Fuzzy         *int          `json:"fuzzy,omitempty"`
+	Boosts        *SearchBoosts `json:"boosts,omitempty"`
+	// Limit is the page size when the reader does not ask for one
+	Limit int `json:"limit,omitempty"`
+}
+
+// normalize trims the filters and rejects values browse and search would
+// otherwise ignore or fail on at read time
+func (p ThemeParams) normalize() (ThemeParams, error) {
+	p.Categories = trimValues(p.Categories)
+	p.Tags = trimValues(p.Tags)
+	p.AuthorSlug = AuthorSlug(p.AuthorSlug)
+
+	switch p.Mode {
+	case "", ModeKeyword, ModeSemantic, ModeHybrid:
+	default:
+		return ThemeParams{}, &ValidationError{Field: "params.mode", Message: "must be keyword, semantic or hybrid"}
+	}
+	if p.Order != "" && p.Order != "asc" && p.Order != "desc" {
+		return ThemeParams{}, &ValidationError{Field: "params.order", Message: "must be asc or desc"}
+	}
+	if p.PopularityMin != nil && p.PopularityMax != nil && *p.PopularityMin > *p.PopularityMax {
+		return ThemeParams{}, &ValidationError{Field: "params.popularity_min", Message: "must not exceed popularity_max"}
+	}
+	for name, date := range map[string]*string{"params.date_from": p.DateFrom, "params.date_to": p.DateTo} {
+		if date == nil {
+			continue
+		}
+		if _, err := time.Parse(time.DateOnly, *date); err != nil {
+			if _, err := time.Parse(time.RFC3339, *date); err != nil {
+				return ThemeParams{}, &ValidationError{Field: name, Message: "must be a date such as 2024-01-31"}
+			}
+		}
+	}
+	if p.Fuzzy != nil && (*p.Fuzzy < 0 || *p.Fuzzy > MaxFuzzyDistance) {
+		return ThemeParams{}, &ValidationError{Field: "params.fuzzy", Message: fmt.Sprintf("must be between 0 and %d", MaxFuzzyDistance)}
+	}
+	if p.Boosts != nil && !p.Boosts.Valid() {
+		return ThemeParams{}, &ValidationError{Field: "params.boosts", Message: "must not be negative and must weight at least one field"}
+	}
+	if p.Limit < 0 || p.Limit > 100 {
+		return ThemeParams{}, &ValidationError{Field: "params.limit", Message: "must be at most 100"}
+	}
+
+	return p, nil
+}
+
+// browseParams applies the theme to the paging of a request. The request's
+// limit wins over the theme's, which wins over the default of 20.
+func (p ThemeParams) browseParams(paging BrowseParams) BrowseParams {
+	limit := paging.Limit
+	if limit == 0 {
+		limit = p.Limit
+	}
+	if limit == 0 {
+		limit = 20
+	}
+
+	return BrowseParams{
+		Page:          max(paging.Page, 1),
+		Limit:         limit,
+		Cursor:        paging.Cursor,
+		IncludeFacets: paging.IncludeFacets,
+		FacetLimit:    paging.FacetLimit,
+		Debug:         paging.Debug,
+		Seed:          paging.Seed,
+		Sort:          p.Sort,
+		Order:         p.Order,
+		Mode:          p.Mode,
+		Categories:    p.Categories,
+		Tags:          p.Tags,
+		PopularityMin: p.PopularityMin,
+		PopularityMax: p.PopularityMax,
+		DateFrom:      p.DateFrom,
+		DateTo:        p.DateTo,
+		AuthorSlug:    p.AuthorSlug,
+		Fuzzy:         p.Fuzzy,
+		Boosts:        p.Boosts,
+	}
+}
+
+func trimValues(values []string) []string {
+	var trimmed []string
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			trimmed = append(trimmed, value)
+		}
+	}
+	return trimmed
+}
+
+// ThemeInput holds the editable fields of a theme. Nil fields are left
+// unchanged by updates; params are replaced as a whole.
+type ThemeInput struct {
+	Slug        *string      `json:"slug"`
+	Title       *string      `json:"title"`
+	Description *string      `json:"description"`
+	Q           *string      `json:"q"`
+	Params      *ThemeParams `json:"params"`
+}
+
+// Normalize applies the collection rules to the slug, title and description,
+// checks that q parses and validates the params. When partial is false the
+// title is required.
+func (in ThemeInput) Normalize(partial bool) (ThemeInput, error) {
+	named, err := CollectionInput{Slug: in.Slug, Title: in.Title, Description: in.Description}.Normalize(partial)
+	if err != nil {
+		return ThemeInput{}, err
+	}
+	out := ThemeInput{Slug: named.Slug, Title: named.Title, Description: named.Description}
+
+	if in.Q != nil {
+		q := strings.TrimSpace(*in.Q)
+		if q != "" {
+			if _, err := ParseQuery(q); err != nil {
+				var parseErr *QueryParseError
+				if errors.As(err, &parseErr) {
+					return ThemeInput{}, &ValidationError{Field: "q", Message: parseErr.Error()}
+				}
+				return ThemeInput{}, err
+			}
+		}
+		out.Q = &q
+	}
+
+	if in.Params != nil {
+		params, err := in.Params.normalize()
+		if err != nil {
+			return ThemeInput{}, err
+		}
+		out.Params = &params
+	}
+
+	return out, nil
+}
+
+// Theme is a named, stored search that is evaluated whenever it is read, so
+// newly ingested quotes appear without editing it
+type Theme struct {
+	ID          int         `json:"id"`
+	Slug        string      `json:"slug"`
+	Title       string      `json:"title"`
+	Description *string     `json:"description,omitempty"`
+	Q           string      `json:"q"`
+	Params      ThemeParams `json:"params"`
+	CreatedAt   *string     `json:"created_at,omitempty"`
+	UpdatedAt   *string     `json:"updated_at,omitempty"`
+}
+
+// validate checks the rules that span fields
+func (t Theme) validate() error {
+	if t.Q == "" && isVectorMode(t.Params.Mode) {
+		return &ValidationError{Field: "params.mode", Message: "semantic and hybrid themes need q"}
+	}
+	return nil
+}
+
+// ThemeResults is a theme with the current page of its results
+type ThemeResults struct {
+	Theme   Theme          `json:"theme"`
+	Results BrowseResponse `json:"results"`
+}
+
+// ThemeListParams represents parameters for the theme directory
+type ThemeListParams struct {
+	Page  int `json:"page"`
+	Limit int `json:"limit"`
+}
+
+// ThemeListResponse represents the response for theme directory API
+type ThemeListResponse struct {
+	Themes     []Theme    `json:"themes"`
+	Pagination Pagination `json:"pagination"`
+}
+
+// ThemeSnapshotInput names the collection a snapshot creates. The slug
+// defaults to the theme's slug and today's date, the title and description
+// to the theme's. Limit is how many results to freeze.
+type ThemeSnapshotInput struct {
+	CollectionInput
+	Limit int `json:"limit"`
+}
+
+const themeColumns = `id, slug, title, description, q, params, created_at, updated_at`
+
+type ThemeQueries struct {
+	db          *pgxpool.Pool
+	search      *SearchQueries
+	browse      *BrowseQueries
+	collections *CollectionQueries
+}
+
+func NewThemeQueries(db *pgxpool.Pool, search *SearchQueries, browse *BrowseQueries, collections *CollectionQueries) *ThemeQueries {
+	return &ThemeQueries{
+		db:          db,
+		search:      search,
+		browse:      browse,
+		collections: collections,
+	}
+}
+
+// ListThemes returns a page of theme definitions ordered by title
+func (tq *ThemeQueries) ListThemes(ctx context.Context, params ThemeListParams) (ThemeListResponse, error) {
+	sql := fmt.Sprintf(`
+		SELECT %s
+		FROM themes
+		ORDER BY title, id
+		LIMIT $1 OFFSET $2
+	`, themeColumns)
+
+	rows, err := tq.db.Query(ctx, sql, params.Limit, (params.Page-1)*params.Limit)
+	if err != nil {
+		return ThemeListResponse{}, err
+	}
+	defer rows.Close()
+
+	themes := []Theme{}
+	for rows.Next() {
+		t, err := scanTheme(rows)
+		if err != nil {
+			return ThemeListResponse{}, err
+		}
+		themes = append(themes, t)
+	}
+	if err := rows.Err(); err != nil {
+		return ThemeListResponse{}, err
+	}
+
+	var totalCount int
+	if err := tq.db.QueryRow(ctx, `SELECT COUNT(*) FROM themes`).Scan(&totalCount); err != nil {
+		return ThemeListResponse{}, err
+	}
+
+	return ThemeListResponse{
+		Themes:     themes,
+		Pagination: tq.browse.buildPagination(params.Page, params.Limit, totalCount),
+	}, nil
+}
+
+// GetTheme returns a theme definition. Returns ErrThemeNotFound.
+func (tq *ThemeQueries) GetTheme(ctx context.Context, slug string) (Theme, error) {
+	sql := fmt.Sprintf(`SELECT %s FROM themes WHERE slug = $1`, themeColumns)
+
+	t, err := scanTheme(tq.db.QueryRow(ctx, sql, slugify(slug)))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Theme{}, ErrThemeNotFound
+	}
+	return t, err
+}
+
+// Evaluate runs a theme now, with paging, facets and debug output taken from
+// paging. Themes with q run through search and others through browse.
+func (tq *ThemeQueries) Evaluate(ctx context.Context, slug string, paging BrowseParams) (ThemeResults, error) {
+	theme, err := tq.GetTheme(ctx, slug)
+	if err != nil {
+		return ThemeResults{}, err
+	}
+
+	results, err := tq.run(ctx, theme, theme.Params.browseParams(paging))
+	if err != nil {
+		return ThemeResults{}, err
+	}
+	return ThemeResults{Theme: theme, Results: results}, nil
+}
+
+func (tq *ThemeQueries) run(ctx context.Context, theme Theme, params BrowseParams) (BrowseResponse, error) {
+	if theme.Q == "" {
+		return tq.browse.Browse(ctx, params)
+	}
+	parsed, err := ParseQuery(theme.Q)
+	if err != nil {
+		return BrowseResponse{}, err
+	}
+	return tq.search.Search(ctx, parsed, params)
+}
+
+// CreateTheme validates and inserts a theme. Returns ErrDuplicateTheme when
+// the slug is taken.
+func (tq *ThemeQueries) CreateTheme(ctx context.Context, input ThemeInput) (Theme, error) {
+	input, err := input.Normalize(false)
+	if err != nil {
+		return Theme{}, err
+	}
+
+	theme := Theme{Slug: *input.Slug, Title: *input.Title, Description: input.Description}
+	if input.Q != nil {
+		theme.Q = *input.Q
+	}
+	if input.Params != nil {
+		theme.Params = *input.Params
+	}
+	if err := theme.validate(); err != nil {
+		return Theme{}, err
+	}
+
+	sql := fmt.Sprintf(`
+		INSERT INTO themes (slug, title, description, q, params, created_at, updated_at)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		RETURNING %s
+	`, themeColumns)
+
+	t, err := scanTheme(tq.db.QueryRow(ctx, sql, theme.Slug, theme.Title, theme.Description, theme.Q, theme.Params))
+	return t, translateThemeError(err)
+}
+
+// UpdateTheme applies the fields set in input to a theme, bumping
+// updated_at. Returns ErrThemeNotFound or ErrDuplicateTheme.
+func (tq *ThemeQueries) UpdateTheme(ctx context.Context, slug string, input ThemeInput) (Theme, error) {
+	input, err := input.Normalize(true)
+	if err != nil {
+		return Theme{}, err
+	}
+	if input == (ThemeInput{}) {
+		return Theme{}, &ValidationError{Field: "body", Message: "no fields to update"}
+	}
+
+	tx, err := tq.db.Begin(ctx)
+	if err != nil {
+		return Theme{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	// q and params are checked together, so the current theme is read first
+	sql := fmt.Sprintf(`SELECT %s FROM themes WHERE slug = $1 FOR UPDATE`, themeColumns)
+	theme, err := scanTheme(tx.QueryRow(ctx, sql, slugify(slug)))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Theme{}, ErrThemeNotFound
+	}
+	if err != nil {
+		return Theme{}, err
+	}
+
+	if input.Slug != nil {
+		theme.Slug = *input.Slug
+	}
+	if input.Title != nil {
+		theme.Title = *input.Title
+	}
+	if input.Description != nil {
+		theme.Description = input.Description
+	}
+	if input.Q != nil {
+		theme.Q = *input.Q
+	}
+	if input.Params != nil {
+		theme.Params = *input.Params
+	}
+	if err := theme.validate(); err != nil {
+		return Theme{}, err
+	}
+
+	sql = fmt.Sprintf(`
+		UPDATE themes
+		SET slug = $1, title = $2, description = NULLIF($3, ''), q = $4, params = $5, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $6
+		RETURNING %s
+	`, themeColumns)
+
+	updated, err := scanTheme(tx.QueryRow(ctx, sql, theme.Slug, theme.Title, theme.Description, theme.Q, theme.Params, theme.ID))
+	if err != nil {
+		return Theme{}, translateThemeError(err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return Theme{}, err
+	}
+	return updated, nil
+}
+
+// DeleteTheme removes a theme. Collections snapshotted from it are kept.
+// Returns ErrThemeNotFound when it does not exist.
+func (tq *ThemeQueries) DeleteTheme(ctx context.Context, slug string) error {
+	tag, err := tq.db.Exec(ctx, `DELETE FROM themes WHERE slug = $1`, slugify(slug))
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrThemeNotFound
+	}
+	return nil
+}
+
+// Snapshot freezes the first results of a theme into a new, unpublished
+// collection that no longer changes as quotes are ingested. Returns
+// ErrThemeNotFound or ErrDuplicateCollection.
+func (tq *ThemeQueries) Snapshot(ctx context.Context, slug string, input ThemeSnapshotInput) (Collection, error) {
+	if input.Limit == 0 {
+		input.Limit = defaultSnapshotLimit
+	}
+	if input.Limit < 0 || input.Limit > MaxCollectionQuotes {
+		return Collection{}, &ValidationError{Field: "limit", Message: fmt.Sprintf("must be between 1 and %d", MaxCollectionQuotes)}
+	}
+
+	theme, err := tq.GetTheme(ctx, slug)
+	if err != nil {
+		return Collection{}, err
+	}
+
+	results, err := tq.run(ctx, theme, theme.Params.browseParams(BrowseParams{Limit: input.Limit}))
+	if err != nil {
+		return Collection{}, err
+	}
+	if len(results.Quotes) == 0 {
+		return Collection{}, &ValidationError{Field: "q", Message: "the theme has no results to snapshot"}
+	}
+	ids := make([]int, len(results.Quotes))
+	for i, q := range results.Quotes {
+		ids[i] = q.ID
+	}
+
+	named := input.CollectionInput
+	if named.Slug == nil {
+		defaultSlug := theme.Slug + "-" + time.Now().UTC().Format(time.DateOnly)
+		named.Slug = &defaultSlug
+	}
+	if named.Title == nil {
+		named.Title = &theme.Title
+	}
+	if named.Description == nil {
+		named.Description = theme.Description
+	}
+
+	return tq.collections.createSnapshot(ctx, named, theme.ID, ids)
+}
+
+// translateThemeError maps the slug unique violation to ErrDuplicateTheme
+func translateThemeError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "uq_themes_slug" {
+		return ErrDuplicateTheme
+	}
+	return err
+}
+
+func scanTheme(row pgx.Row) (Theme, error) {
+	var t Theme
+	var params []byte
+	var createdAt, updatedAt *time.Time
+
+	err := row.Scan(&t.ID, &t.Slug, &t.Title, &t.Description, &t.Q, &params, &createdAt, &updatedAt)
+	if err != nil {
+		return Theme{}, err
+	}
+	if err := json.Unmarshal(params, &t.Params); err != nil {
+		return Theme{}, fmt.Errorf("theme %s params: %w", t.Slug, err)
+	}
+
+	if createdAt != nil {
+		createdAtStr := createdAt.Format(time.RFC3339)
+		t.CreatedAt = &createdAtStr
+	}
+	if updatedAt != nil {
+		updatedAtStr := updatedAt.Format(time.RFC3339)
+		t.UpdatedAt = &updatedAtStr
+	}
+
+	return t, nil
+}
@@ -0,0 +1,70 @@
+package queries
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestThemeInputNormalize(t *testing.T) {
+	title := "Courage in life"
+	q := "  courage  "
+	min := 0.1
+	in, err := ThemeInput{Title: &title, Q: &q, Params: &ThemeParams{
+		Categories:    []string{" life ", ""},
+		PopularityMin: &min,
+		AuthorSlug:    "Dr. Seuss",
+	}}.Normalize(false)
+	if err != nil {
+		t.Fatalf("Normalize: %v", err)
+	}
+	if *in.Slug != "courage-in-life" || *in.Q != "courage" || len(in.Params.Categories) != 1 || in.Params.Categories[0] != "life" || in.Params.AuthorSlug != "dr-seuss" {
+		t.Errorf("Normalize = slug %q, q %q, params %+v", *in.Slug, *in.Q, *in.Params)
+	}
+
+	badQuery := `life "never give`
+	badDate := "yesterday"
+	fuzzy := 5
+	for name, input := range map[string]ThemeInput{
+		"q":     {Title: &title, Q: &badQuery},
+		"mode":  {Title: &title, Params: &ThemeParams{Mode: "vibes"}},
+		"order": {Title: &title, Params: &ThemeParams{Order: "up"}},
+		"date":  {Title: &title, Params: &ThemeParams{DateFrom: &badDate}},
+		"fuzzy": {Title: &title, Params: &ThemeParams{Fuzzy: &fuzzy}},
+		"limit": {Title: &title, Params: &ThemeParams{Limit: 500}},
+		"boost": {Title: &title, Params: &ThemeParams{Boosts: &SearchBoosts{}}},
+	} {
+		var validationErr *ValidationError
+		if _, err := input.Normalize(false); !errors.As(err, &validationErr) {
+			t.Errorf("%s: Normalize error = %v, want a ValidationError", name, err)
+		}
+	}
+
+	if err := (Theme{Params: ThemeParams{Mode: ModeSemantic}}).validate(); err == nil {
+		t.Errorf("validate accepted a semantic theme without q")
+	}
+}
+
+func TestThemeBrowseParams(t *testing.T) {
+	theme := ThemeParams{Sort: SortPopularity, Tags: []string{"life"}, Limit: 12}
+
+	params := theme.browseParams(BrowseParams{Page: 3, IncludeFacets: true})
+	if params.Page != 3 || params.Limit != 12 || !params.IncludeFacets || params.Sort != SortPopularity || params.Tags[0] != "life" {
+		t.Errorf("browseParams = %+v", params)
+	}
+
+	// The reader's limit wins, and filters always come from the theme
+	params = theme.browseParams(BrowseParams{Limit: 5, Tags: []string{"love"}})
+	if params.Page != 1 || params.Limit != 5 || params.Tags[0] != "life" {
+		t.Errorf("browseParams with a reader limit = %+v", params)
+	}
+
+	// A seed keeps a random theme stable across pages
+	seed := int64(7)
+	if params = (ThemeParams{Sort: "random"}).browseParams(BrowseParams{Seed: &seed}); params.Seed == nil || *params.Seed != 7 {
+		t.Errorf("browseParams seed = %v, want 7", params.Seed)
+	}
+
+	if params = (ThemeParams{}).browseParams(BrowseParams{}); params.Limit != 20 {
+		t.Errorf("browseParams default limit = %d, want 20", params.Limit)
+	}
+}
@@ -1,5 +1,10 @@
 package queries
 
+import (
+	"math"
+	"time"
+)
+
 // Quote represents a quote from the database
 type Quote struct {
 	ID               int      `json:"id"`
@@ -11,6 +16,12 @@ type Quote struct {
 	HighlightedQuote *string  `json:"highlighted_quote,omitempty"`
 	Popularity       *float64 `json:"popularity,omitempty"`
 	CreatedAt        *string  `json:"created_at,omitempty"`
+	UpdatedAt        *string  `json:"updated_at,omitempty"`
+	ShareURL         string   `json:"share_url,omitempty"`
+	// ScoreComponents is set on search results when debug output is requested
+	ScoreComponents *ScoreComponents `json:"score_components,omitempty"`
+	// IsFavorite is set when the request identifies a user
+	IsFavorite *bool `json:"is_favorite,omitempty"`
 }
 
 // SearchResponse represents the response for search API
@@ -28,6 +39,8 @@ type Pagination struct {
 	TotalCount int  `json:"total_count"`
 	HasNext    bool `json:"has_next"`
 	HasPrev    bool `json:"has_prev"`
+	// NextCursor continues after this page with keyset paging
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 // FacetItem represents a single facet value with count
@@ -65,20 +78,117 @@ type BrowseResponse struct {
 	Pagination    Pagination    `json:"pagination"`
 	Facets        *Facets       `json:"facets,omitempty"`
 	ActiveFilters ActiveFilters `json:"active_filters"`
+	FuzzyApplied  bool          `json:"fuzzy_applied,omitempty"`
+	Suggestions   []string      `json:"suggestions,omitempty"`
+	// FacetsTimedOut is set when facets were requested but took too long
+	FacetsTimedOut bool `json:"facets_timed_out,omitempty"`
 }
 
 // BrowseParams represents parameters for browse queries
 type BrowseParams struct {
-	Page          int      `json:"page"`
-	Limit         int      `json:"limit"`
-	Sort          string   `json:"sort"`
-	Order         string   `json:"order"`
-	Categories    []string `json:"categories"`
-	Tags          []string `json:"tags"`
-	PopularityMin *float64 `json:"popularity_min"`
-	PopularityMax *float64 `json:"popularity_max"`
-	DateFrom      *string  `json:"date_from"`
-	DateTo        *string  `json:"date_to"`
-	IncludeFacets bool     `json:"include_facets"`
-	FacetLimit    int      `json:"facet_limit"`
-}
\ No newline at end of file
+	Page          int           `json:"page"`
+	Limit         int           `json:"limit"`
+	Cursor        string        `json:"cursor,omitempty"`
+	Sort          string        `json:"sort"`
+	Order         string        `json:"order"`
+	Categories    []string      `json:"categories"`
+	Tags          []string      `json:"tags"`
+	PopularityMin *float64      `json:"popularity_min"`
+	PopularityMax *float64      `json:"popularity_max"`
+	DateFrom      *string       `json:"date_from"`
+	DateTo        *string       `json:"date_to"`
+	IncludeFacets bool          `json:"include_facets"`
+	FacetLimit    int           `json:"facet_limit"`
+	Boosts        *SearchBoosts `json:"boosts,omitempty"`
+	Fuzzy         *int          `json:"fuzzy,omitempty"`
+	AuthorSlug    string        `json:"author_slug,omitempty"`
+	Debug         bool          `json:"debug,omitempty"`
+	// Mode is keyword (BM25, the default), semantic or hybrid
+	Mode string `json:"mode,omitempty"`
+	// Seed makes sort=random a stable order that can be paged
+	Seed *int64 `json:"seed,omitempty"`
+	// FavoritesOf restricts results to the favorites of a user ID when set
+	FavoritesOf int `json:"-"`
+}
+
+// SearchBoosts weights matches in each BM25-indexed field
+type SearchBoosts struct {
+	Quote    float64 `json:"quote"`
+	Author   float64 `json:"author"`
+	Tags     float64 `json:"tags"`
+	Category float64 `json:"category"`
+}
+
+// DefaultSearchBoosts returns the boosts used when none are configured
+func DefaultSearchBoosts() SearchBoosts {
+	return SearchBoosts{
+		Quote:    1.0,
+		Author:   3.0,
+		Tags:     2.0,
+		Category: 1.5,
+	}
+}
+
+// Valid reports whether every boost is finite and not negative and at least
+// one field is weighted, so unscoped terms have a field to search
+func (b SearchBoosts) Valid() bool {
+	weighted := false
+	for _, fb := range b.fields() {
+		if fb.boost < 0 || math.IsNaN(fb.boost) || math.IsInf(fb.boost, 0) {
+			return false
+		}
+		weighted = weighted || fb.boost > 0
+	}
+	return weighted
+}
+
+type fieldBoost struct {
+	field string
+	boost float64
+}
+
+func (b SearchBoosts) fields() []fieldBoost {
+	return []fieldBoost{
+		{"quote", b.Quote},
+		{"author", b.Author},
+		{"tags", b.Tags},
+		{"category", b.Category},
+	}
+}
+
+// SearchConfig holds server-wide search settings
+type SearchConfig struct {
+	Boosts SearchBoosts
+	// SuggestionThreshold is the hit count below which spelling suggestions are added
+	SuggestionThreshold int
+	// FacetTimeout bounds facet queries; slower facets are omitted from the response
+	FacetTimeout time.Duration
+	// SingleQuery fetches the page, total count and facets in one statement
+	// instead of concurrent queries
+	SingleQuery bool
+	// Ranking weighs the blended search sort
+	Ranking RankingWeights
+	// SemanticCandidates is how many nearest quotes, and for hybrid search
+	// BM25 matches, are ranked
+	SemanticCandidates int
+	// RRFK dampens rank differences in hybrid reciprocal rank fusion
+	RRFK int
+}
+
+// DefaultSearchConfig returns the search settings used when none are configured
+func DefaultSearchConfig() SearchConfig {
+	return SearchConfig{
+		Boosts:              DefaultSearchBoosts(),
+		SuggestionThreshold: 3,
+		FacetTimeout:        2 * time.Second,
+		Ranking:             DefaultRankingWeights(),
+		SemanticCandidates:  100,
+		RRFK:                60,
+	}
+}
+
+// SpellingResponse represents the response for the spelling suggestion API
+type SpellingResponse struct {
+	Query       string   `json:"query"`
+	Suggestions []string `json:"suggestions"`
+}
@@ -0,0 +1,122 @@
+package queries
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrUnknownAPIKey is returned when an API key belongs to no user
+var ErrUnknownAPIKey = errors.New("unknown API key")
+
+// apiKeyPrefix marks user API keys, so other credentials such as the admin
+// key are told apart without a database lookup
+const apiKeyPrefix = "qk_"
+
+// maxUserName matches the column size of users.name
+const maxUserName = 100
+
+// User is an API client with its own favorites and personal collections
+type User struct {
+	ID        int     `json:"id"`
+	Name      string  `json:"name"`
+	CreatedAt *string `json:"created_at,omitempty"`
+}
+
+// NewUser is a created user with its API key, which is only ever shown once
+type NewUser struct {
+	User
+	APIKey string `json:"api_key"`
+}
+
+type UserQueries struct {
+	db *pgxpool.Pool
+}
+
+func NewUserQueries(db *pgxpool.Pool) *UserQueries {
+	return &UserQueries{db: db}
+}
+
+// CreateUser adds a user and issues its API key. Only a hash of the key is
+// stored, so a lost key is replaced by creating another user.
+func (uq *UserQueries) CreateUser(ctx context.Context, name string) (NewUser, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return NewUser{}, &ValidationError{Field: "name", Message: "is required"}
+	}
+	if utf8.RuneCountInString(name) > maxUserName {
+		return NewUser{}, &ValidationError{Field: "name", Message: fmt.Sprintf("must be at most %d characters", maxUserName)}
+	}
+
+	key, err := generateAPIKey()
+	if err != nil {
+		return NewUser{}, err
+	}
+
+	user, err := scanUser(uq.db.QueryRow(ctx, `
+		INSERT INTO users (name, api_key_hash, created_at)
+		VALUES ($1, $2, CURRENT_TIMESTAMP)
+		RETURNING id, name, created_at
+	`, name, hashAPIKey(key)))
+	if err != nil {
+		return NewUser{}, err
+	}
+	return NewUser{User: user, APIKey: key}, nil
+}
+
+// Authenticate returns the user an API key belongs to, or ErrUnknownAPIKey
+func (uq *UserQueries) Authenticate(ctx context.Context, key string) (User, error) {
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return User{}, ErrUnknownAPIKey
+	}
+
+	user, err := scanUser(uq.db.QueryRow(ctx, `
+		SELECT id, name, created_at
+		FROM users
+		WHERE api_key_hash = $1
+	`, hashAPIKey(key)))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return User{}, ErrUnknownAPIKey
+	}
+	return user, err
+}
+
+// generateAPIKey returns a new random key with apiKeyPrefix
+func generateAPIKey() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return apiKeyPrefix + hex.EncodeToString(secret), nil
+}
+
+// hashAPIKey returns the SHA-256 hex digest stored for a key. Keys are long
+// and random, so an unsalted fast hash is enough to make them unusable if the
+// table leaks.
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func scanUser(row pgx.Row) (User, error) {
+	var u User
+	var createdAt *time.Time
+
+	if err := row.Scan(&u.ID, &u.Name, &createdAt); err != nil {
+		return User{}, err
+	}
+	if createdAt != nil {
+		createdAtStr := createdAt.Format(time.RFC3339)
+		u.CreatedAt = &createdAtStr
+	}
+	return u, nil
+}
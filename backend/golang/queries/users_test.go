@@ -0,0 +1,31 @@
+package queries
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestAPIKeys(t *testing.T) {
+	key, err := generateAPIKey()
+	if err != nil {
+		t.Fatalf("generateAPIKey: %v", err)
+	}
+	other, _ := generateAPIKey()
+	if !strings.HasPrefix(key, apiKeyPrefix) || len(key) != len(apiKeyPrefix)+64 || key == other {
+		t.Errorf("generateAPIKey = %q, %q; want distinct %s keys with 64 hex digits", key, other, apiKeyPrefix)
+	}
+
+	hash := hashAPIKey(key)
+	if len(hash) != 64 || hash != hashAPIKey(key) || hash == hashAPIKey(other) {
+		t.Errorf("hashAPIKey(%q) = %q, want a stable 64-digit hex digest", key, hash)
+	}
+
+	// Keys without the prefix are rejected before the database is queried
+	if _, err := NewUserQueries(nil).Authenticate(context.Background(), "admin-secret"); err != ErrUnknownAPIKey {
+		t.Errorf("Authenticate without prefix error = %v, want ErrUnknownAPIKey", err)
+	}
+	if _, err := NewUserQueries(nil).CreateUser(context.Background(), "  "); err == nil {
+		t.Errorf("CreateUser accepted a blank name")
+	}
+}
@@ -0,0 +1,98 @@
+package releval
+
+import "sort"
+
+// QueryDiff compares one query across two runs
+type QueryDiff struct {
+	ID     string  `json:"id"`
+	Query  string  `json:"q"`
+	Before Metrics `json:"before"`
+	After  Metrics `json:"after"`
+	Delta  Metrics `json:"delta"`
+}
+
+// Diff compares two runs of the same judgment file
+type Diff struct {
+	Before  Metrics     `json:"before"`
+	After   Metrics     `json:"after"`
+	Delta   Metrics     `json:"delta"`
+	Queries []QueryDiff `json:"queries"`
+	// OnlyBefore and OnlyAfter list query IDs judged in just one run; they
+	// are left out of the means
+	OnlyBefore []string `json:"only_before,omitempty"`
+	OnlyAfter  []string `json:"only_after,omitempty"`
+}
+
+// Compare diffs the queries two runs share. Queries are ordered by nDCG
+// change, worst regression first.
+func Compare(before, after Run) Diff {
+	afterByID := make(map[string]QueryResult, len(after.Queries))
+	for _, q := range after.Queries {
+		afterByID[q.ID] = q
+	}
+
+	var diff Diff
+	var beforeMetrics, afterMetrics []Metrics
+	shared := make(map[string]bool)
+	for _, b := range before.Queries {
+		a, ok := afterByID[b.ID]
+		if !ok {
+			diff.OnlyBefore = append(diff.OnlyBefore, b.ID)
+			continue
+		}
+		shared[b.ID] = true
+		diff.Queries = append(diff.Queries, QueryDiff{
+			ID:     b.ID,
+			Query:  b.Query,
+			Before: b.Metrics,
+			After:  a.Metrics,
+			Delta:  subtract(a.Metrics, b.Metrics),
+		})
+		beforeMetrics = append(beforeMetrics, b.Metrics)
+		afterMetrics = append(afterMetrics, a.Metrics)
+	}
+	for _, a := range after.Queries {
+		if !shared[a.ID] {
+			diff.OnlyAfter = append(diff.OnlyAfter, a.ID)
+		}
+	}
+
+	sort.SliceStable(diff.Queries, func(i, j int) bool {
+		return diff.Queries[i].Delta.NDCG < diff.Queries[j].Delta.NDCG
+	})
+
+	diff.Before = mean(beforeMetrics)
+	diff.After = mean(afterMetrics)
+	diff.Delta = subtract(diff.After, diff.Before)
+	return diff
+}
+
+// Regressions returns the queries whose metric fell by more than tolerance
+func (d Diff) Regressions(metric string, tolerance float64) []QueryDiff {
+	var regressions []QueryDiff
+	for _, q := range d.Queries {
+		if q.Delta.Get(metric) < -tolerance {
+			regressions = append(regressions, q)
+		}
+	}
+	return regressions
+}
+
+// ValidMetric reports whether name is a metric Regressions accepts
+func ValidMetric(name string) bool {
+	for _, metric := range MetricNames {
+		if metric == name {
+			return true
+		}
+	}
+	return false
+}
+
+func subtract(a, b Metrics) Metrics {
+	return Metrics{
+		NDCG:      a.NDCG - b.NDCG,
+		MRR:       a.MRR - b.MRR,
+		Precision: a.Precision - b.Precision,
+		Recall:    a.Recall - b.Recall,
+	}
+}
@@ -0,0 +1,64 @@
+package releval
+
+import (
+	"context"
+	"testing"
+
+	"quotes-api/queries"
+)
+
+// fakeSearcher returns fixed results per raw query
+type fakeSearcher map[string][]int
+
+func (f fakeSearcher) Search(ctx context.Context, query *queries.ParsedQuery, params queries.BrowseParams) (queries.BrowseResponse, error) {
+	var response queries.BrowseResponse
+	for i, id := range f[query.Raw] {
+		if i == params.Limit {
+			break
+		}
+		response.Quotes = append(response.Quotes, queries.Quote{ID: id})
+	}
+	return response, nil
+}
+
+func TestEvaluateAndCompare(t *testing.T) {
+	judgments, err := LoadJudgments("testdata/judgments.json")
+	if err != nil {
+		t.Fatalf("LoadJudgments: %v", err)
+	}
+
+	before, err := Evaluate(context.Background(), fakeSearcher{
+		"courage":              {1, 2, 3},
+		"author:churchill war": {5, 4},
+	}, judgments, Options{Label: "before"})
+	if err != nil {
+		t.Fatalf("Evaluate: %v", err)
+	}
+	if before.Options.K != 5 || before.Queries[0].Metrics.NDCG != 1 || before.Queries[1].Metrics.MRR != 0.5 {
+		t.Errorf("Evaluate = %+v", before)
+	}
+
+	after, err := Evaluate(context.Background(), fakeSearcher{
+		"courage":              {3, 2, 1},
+		"author:churchill war": {4},
+	}, judgments, Options{Label: "after"})
+	if err != nil {
+		t.Fatalf("Evaluate: %v", err)
+	}
+
+	diff := Compare(before, after)
+	if len(diff.Queries) != 2 || diff.Queries[0].ID != "courage" {
+		t.Fatalf("Compare queries = %+v, want the courage regression first", diff.Queries)
+	}
+	if diff.Queries[1].Delta.MRR != 0.5 {
+		t.Errorf("churchill-war MRR delta = %v, want 0.5", diff.Queries[1].Delta.MRR)
+	}
+	if regressions := diff.Regressions("ndcg", 0.01); len(regressions) != 1 || regressions[0].ID != "courage" {
+		t.Errorf("Regressions = %+v, want courage", regressions)
+	}
+
+	after.Queries = after.Queries[:1]
+	if diff := Compare(before, after); len(diff.OnlyBefore) != 1 || diff.OnlyBefore[0] != "churchill-war" {
+		t.Errorf("Compare OnlyBefore = %v, want [churchill-war]", diff.OnlyBefore)
+	}
+}
@@ -0,0 +1,81 @@
+// Package releval measures search relevance against judgment lists: queries
+// with graded relevant quote IDs. Runs score each query with nDCG@k, MRR,
+// precision and recall, and two runs can be diffed to gate ranking changes.
+package releval
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Judgments is a judgment file:
+//
+//	{
+//	  "k": 10,
+//	  "queries": [
+//	    {"id": "churchill-courage", "q": "courage author:churchill",
+//	     "grades": {"412": 3, "977": 1}}
+//	  ]
+//	}
+//
+// Grades run from 0 (not relevant) upwards; unjudged quotes count as 0.
+type Judgments struct {
+	// K is the default cutoff when the run does not set one
+	K       int           `json:"k,omitempty"`
+	Queries []JudgedQuery `json:"queries"`
+}
+
+// JudgedQuery is one query of a judgment file with its graded quote IDs
+type JudgedQuery struct {
+	ID         string      `json:"id"`
+	Query      string      `json:"q"`
+	Categories []string    `json:"categories,omitempty"`
+	Tags       []string    `json:"tags,omitempty"`
+	Grades     map[int]int `json:"grades"`
+}
+
+// LoadJudgments reads and validates a judgment file
+func LoadJudgments(path string) (Judgments, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Judgments{}, err
+	}
+
+	var judgments Judgments
+	if err := json.Unmarshal(data, &judgments); err != nil {
+		return Judgments{}, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := judgments.validate(); err != nil {
+		return Judgments{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return judgments, nil
+}
+
+func (j Judgments) validate() error {
+	if len(j.Queries) == 0 {
+		return fmt.Errorf("no queries")
+	}
+
+	seen := make(map[string]bool)
+	for i, query := range j.Queries {
+		if query.ID == "" {
+			return fmt.Errorf("query %d: id is required", i)
+		}
+		if seen[query.ID] {
+			return fmt.Errorf("query %q: duplicate id", query.ID)
+		}
+		seen[query.ID] = true
+
+		if strings.TrimSpace(query.Query) == "" {
+			return fmt.Errorf("query %q: q is required", query.ID)
+		}
+		for id, grade := range query.Grades {
+			if grade < 0 {
+				return fmt.Errorf("query %q: grade of quote %d is negative", query.ID, id)
+			}
+		}
+	}
+	return nil
+}
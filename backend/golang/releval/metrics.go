@@ -0,0 +1,115 @@
+package releval
+
+import (
+	"math"
+	"sort"
+)
+
+// Metrics scores one ranked result list against its grades
+type Metrics struct {
+	NDCG      float64 `json:"ndcg"`
+	MRR       float64 `json:"mrr"`
+	Precision float64 `json:"precision"`
+	Recall    float64 `json:"recall"`
+}
+
+// MetricNames lists the metrics in report order
+var MetricNames = []string{"ndcg", "mrr", "precision", "recall"}
+
+// Get returns a metric by its JSON name
+func (m Metrics) Get(name string) float64 {
+	switch name {
+	case "ndcg":
+		return m.NDCG
+	case "mrr":
+		return m.MRR
+	case "precision":
+		return m.Precision
+	default:
+		return m.Recall
+	}
+}
+
+// Score computes the metrics of the top k of ranked, a list of quote IDs in
+// result order. A quote is relevant when its grade is above zero. nDCG uses
+// the exponential gain 2^grade - 1, and precision divides by k even when
+// fewer results came back.
+func Score(ranked []int, grades map[int]int, k int) Metrics {
+	if len(ranked) > k {
+		ranked = ranked[:k]
+	}
+
+	relevant := 0
+	for _, grade := range grades {
+		if grade > 0 {
+			relevant++
+		}
+	}
+
+	var metrics Metrics
+	var dcg float64
+	hits := 0
+	for i, id := range ranked {
+		grade := grades[id]
+		if grade <= 0 {
+			continue
+		}
+		dcg += gain(grade, i)
+		hits++
+		if metrics.MRR == 0 {
+			metrics.MRR = 1 / float64(i+1)
+		}
+	}
+
+	if ideal := idealDCG(grades, k); ideal > 0 {
+		metrics.NDCG = dcg / ideal
+	}
+	if k > 0 {
+		metrics.Precision = float64(hits) / float64(k)
+	}
+	if relevant > 0 {
+		metrics.Recall = float64(hits) / float64(relevant)
+	}
+	return metrics
+}
+
+// gain is the discounted gain of a grade at a zero-based rank
+func gain(grade, rank int) float64 {
+	return (math.Pow(2, float64(grade)) - 1) / math.Log2(float64(rank)+2)
+}
+
+// idealDCG is the DCG of the best possible top k
+func idealDCG(grades map[int]int, k int) float64 {
+	var sorted []int
+	for _, grade := range grades {
+		if grade > 0 {
+			sorted = append(sorted, grade)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(sorted)))
+	if len(sorted) > k {
+		sorted = sorted[:k]
+	}
+
+	var dcg float64
+	for i, grade := range sorted {
+		dcg += gain(grade, i)
+	}
+	return dcg
+}
+
+// mean averages metrics over queries
+func mean(all []Metrics) Metrics {
+	var sum Metrics
+	if len(all) == 0 {
+		return sum
+	}
+	for _, m := range all {
+		sum.NDCG += m.NDCG
+		sum.MRR += m.MRR
+		sum.Precision += m.Precision
+		sum.Recall += m.Recall
+	}
+	n := float64(len(all))
+	return Metrics{NDCG: sum.NDCG / n, MRR: sum.MRR / n, Precision: sum.Precision / n, Recall: sum.Recall / n}
+}
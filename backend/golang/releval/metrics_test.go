@@ -0,0 +1,58 @@
+package releval
+
+import (
+	"math"
+	"testing"
+)
+
+func approx(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestScore(t *testing.T) {
+	grades := map[int]int{1: 3, 2: 2, 3: 1, 9: 0}
+
+	perfect := Score([]int{1, 2, 3}, grades, 3)
+	if !approx(perfect.NDCG, 1) || perfect.MRR != 1 || perfect.Precision != 1 || perfect.Recall != 1 {
+		t.Errorf("Score of the ideal ranking = %+v, want all 1", perfect)
+	}
+
+	// The top result is unjudged, the second is the grade-1 quote
+	m := Score([]int{7, 3, 8, 2, 1}, grades, 4)
+	dcg := 1/math.Log2(3) + 3/math.Log2(5)
+	ideal := 7 + 3/math.Log2(3) + 1/math.Log2(4)
+	if !approx(m.NDCG, dcg/ideal) {
+		t.Errorf("NDCG = %v, want %v", m.NDCG, dcg/ideal)
+	}
+	if !approx(m.MRR, 0.5) || !approx(m.Precision, 0.5) || !approx(m.Recall, 2.0/3) {
+		t.Errorf("Score = %+v, want mrr 0.5, precision 0.5, recall 2/3", m)
+	}
+
+	if none := Score(nil, grades, 10); none != (Metrics{}) {
+		t.Errorf("Score of no results = %+v, want zero", none)
+	}
+	if unjudged := Score([]int{1}, map[int]int{}, 10); unjudged.NDCG != 0 || unjudged.Recall != 0 {
+		t.Errorf("Score without relevant quotes = %+v, want zero", unjudged)
+	}
+}
+
+func TestLoadJudgments(t *testing.T) {
+	judgments, err := LoadJudgments("testdata/judgments.json")
+	if err != nil {
+		t.Fatalf("LoadJudgments: %v", err)
+	}
+	if judgments.K != 5 || len(judgments.Queries) != 2 || judgments.Queries[0].Grades[2] != 2 {
+		t.Errorf("LoadJudgments = %+v", judgments)
+	}
+
+	for _, invalid := range []Judgments{
+		{},
+		{Queries: []JudgedQuery{{ID: "a", Query: " "}}},
+		{Queries: []JudgedQuery{{ID: "a", Query: "x"}, {ID: "a", Query: "y"}}},
+		{Queries: []JudgedQuery{{ID: "a", Query: "x", Grades: map[int]int{1: -1}}}},
+	} {
+		if err := invalid.validate(); err == nil {
+			t.Errorf("validate(%+v) = nil, want an error", invalid)
+		}
+	}
+}
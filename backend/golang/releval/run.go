@@ -0,0 +1,113 @@
+package releval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"quotes-api/queries"
+)
+
+// Searcher runs a parsed search; *queries.SearchQueries satisfies it
+type Searcher interface {
+	Search(ctx context.Context, query *queries.ParsedQuery, params queries.BrowseParams) (queries.BrowseResponse, error)
+}
+
+// Options control how judged queries are searched
+type Options struct {
+	// K is the cutoff; zero uses the judgment file's, or 10
+	K int `json:"k"`
+	// Sort is passed to the search as the sort parameter
+	Sort string `json:"sort,omitempty"`
+	// Mode is keyword, semantic or hybrid
+	Mode string `json:"mode,omitempty"`
+	// Boosts overrides the searcher's configured field boosts when set
+	Boosts *queries.SearchBoosts `json:"boosts,omitempty"`
+	// Label names the run in diffs, such as "before" or "author-boost-4"
+	Label string `json:"label,omitempty"`
+}
+
+// QueryResult is the outcome of one judged query
+type QueryResult struct {
+	ID      string  `json:"id"`
+	Query   string  `json:"q"`
+	Results []int   `json:"results"`
+	Metrics Metrics `json:"metrics"`
+}
+
+// Run is the outcome of a judgment file, saved for later diffs
+type Run struct {
+	Options   Options       `json:"options"`
+	StartedAt time.Time     `json:"started_at"`
+	Mean      Metrics       `json:"mean"`
+	Queries   []QueryResult `json:"queries"`
+}
+
+// Evaluate searches every judged query and scores the top k results
+func Evaluate(ctx context.Context, searcher Searcher, judgments Judgments, options Options) (Run, error) {
+	if options.K <= 0 {
+		options.K = judgments.K
+	}
+	if options.K <= 0 {
+		options.K = 10
+	}
+
+	run := Run{Options: options, StartedAt: time.Now().UTC()}
+	var all []Metrics
+	for _, judged := range judgments.Queries {
+		parsed, err := queries.ParseQuery(judged.Query)
+		if err != nil {
+			return Run{}, fmt.Errorf("query %q: %w", judged.ID, err)
+		}
+
+		params := queries.BrowseParams{
+			Page:       1,
+			Limit:      options.K,
+			Sort:       options.Sort,
+			Mode:       options.Mode,
+			Order:      "desc",
+			Categories: judged.Categories,
+			Tags:       judged.Tags,
+			Boosts:     options.Boosts,
+		}
+		response, err := searcher.Search(ctx, parsed, params)
+		if err != nil {
+			return Run{}, fmt.Errorf("query %q: %w", judged.ID, err)
+		}
+
+		ranked := make([]int, len(response.Quotes))
+		for i, q := range response.Quotes {
+			ranked[i] = q.ID
+		}
+		metrics := Score(ranked, judged.Grades, options.K)
+		run.Queries = append(run.Queries, QueryResult{ID: judged.ID, Query: judged.Query, Results: ranked, Metrics: metrics})
+		all = append(all, metrics)
+	}
+
+	run.Mean = mean(all)
+	return run, nil
+}
+
+// LoadRun reads a run saved with Save
+func LoadRun(path string) (Run, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Run{}, err
+	}
+	var run Run
+	if err := json.Unmarshal(data, &run); err != nil {
+		return Run{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return run, nil
+}
+
+// Save writes the run as indented JSON
+func (r Run) Save(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
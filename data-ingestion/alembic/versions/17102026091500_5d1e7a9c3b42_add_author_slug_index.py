@@ -0,0 +1,30 @@
+"""add author slug expression index
+
+Revision ID: 5d1e7a9c3b42
+Revises: 3b676a17fcea
+Create Date: 2026-10-17 09:15:00.000000
+
+"""
+from alembic import op
+import sqlalchemy as sa
+
+
+# revision identifiers, used by Alembic.
+revision = '5d1e7a9c3b42'
+down_revision = '3b676a17fcea'
+branch_labels = None
+depends_on = None
+
+
+def upgrade() -> None:
+    # Index the author slug used by the Go API's author directory and profiles.
+    # The expression must match authorSlugSQL in backend/golang/queries/authors.go
+    op.execute("""
+        CREATE INDEX idx_quotes_author_slug ON quotes
+        ((trim(both '-' from regexp_replace(lower(author), '[^[:alnum:]]+', '-', 'g'))))
+    """)
+
+
+def downgrade() -> None:
+    # Drop author slug index
+    op.execute("DROP INDEX IF EXISTS idx_quotes_author_slug")
@@ -0,0 +1,46 @@
+"""add quote embeddings for semantic search
+
+Revision ID: 8e4f2b6a1d73
+Revises: 5d1e7a9c3b42
+Create Date: 2026-10-17 10:15:00.000000
+
+"""
+from alembic import op
+import sqlalchemy as sa
+
+
+# revision identifiers, used by Alembic.
+revision = '8e4f2b6a1d73'
+down_revision = '5d1e7a9c3b42'
+branch_labels = None
+depends_on = None
+
+
+def upgrade() -> None:
+    # pgvector ships with ParadeDB
+    op.execute("CREATE EXTENSION IF NOT EXISTS vector")
+
+    # The dimension must match EmbeddingDimensions in backend/golang/queries/embedder.go.
+    # embedding_model records which embedder produced the vector, so a model
+    # change is re-embedded by the API server in the background
+    op.execute("""
+        ALTER TABLE quotes
+            ADD COLUMN embedding vector(256),
+            ADD COLUMN embedding_model VARCHAR(100)
+    """)
+
+    # Approximate nearest neighbour index for cosine distance
+    op.execute("""
+        CREATE INDEX idx_quotes_embedding ON quotes
+        USING hnsw (embedding vector_cosine_ops)
+    """)
+
+
+def downgrade() -> None:
+    # Drop embeddings and their index
+    op.execute("DROP INDEX IF EXISTS idx_quotes_embedding")
+    op.execute("""
+        ALTER TABLE quotes
+            DROP COLUMN IF EXISTS embedding_model,
+            DROP COLUMN IF EXISTS embedding
+    """)
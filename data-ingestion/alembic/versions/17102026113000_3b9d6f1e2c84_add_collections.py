@@ -0,0 +1,54 @@
+"""add curated quote collections
+
+Revision ID: 3b9d6f1e2c84
+Revises: 8e4f2b6a1d73
+Create Date: 2026-10-17 11:30:00.000000
+
+"""
+from alembic import op
+import sqlalchemy as sa
+
+
+# revision identifiers, used by Alembic.
+revision = '3b9d6f1e2c84'
+down_revision = '8e4f2b6a1d73'
+branch_labels = None
+depends_on = None
+
+
+def upgrade() -> None:
+    # A collection is public once published_at is set
+    op.execute("""
+        CREATE TABLE collections (
+            id SERIAL PRIMARY KEY,
+            slug VARCHAR(100) NOT NULL,
+            title VARCHAR(200) NOT NULL,
+            description TEXT,
+            cover_theme VARCHAR(50),
+            published_at TIMESTAMP,
+            created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+            updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+            CONSTRAINT uq_collections_slug UNIQUE (slug)
+        )
+    """)
+
+    # Ordered membership; removing a quote or collection removes its entries
+    op.execute("""
+        CREATE TABLE collection_quotes (
+            collection_id INTEGER NOT NULL REFERENCES collections(id) ON DELETE CASCADE,
+            quote_id INTEGER NOT NULL REFERENCES quotes(id) ON DELETE CASCADE,
+            position INTEGER NOT NULL,
+            added_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+            PRIMARY KEY (collection_id, quote_id)
+        )
+    """)
+
+    op.execute("CREATE INDEX idx_collection_quotes_position ON collection_quotes(collection_id, position)")
+    op.execute("CREATE INDEX idx_collection_quotes_quote_id ON collection_quotes(quote_id)")
+    op.execute("CREATE INDEX idx_collections_published_at ON collections(published_at DESC) WHERE published_at IS NOT NULL")
+
+
+def downgrade() -> None:
+    # Drop collections and their entries
+    op.execute("DROP TABLE IF EXISTS collection_quotes")
+    op.execute("DROP TABLE IF EXISTS collections")
@@ -0,0 +1,46 @@
+"""add saved-query theme definitions
+
+Revision ID: c51a8e7d4f06
+Revises: 3b9d6f1e2c84
+Create Date: 2026-10-17 12:00:00.000000
+
+"""
+from alembic import op
+import sqlalchemy as sa
+
+
+# revision identifiers, used by Alembic.
+revision = 'c51a8e7d4f06'
+down_revision = '3b9d6f1e2c84'
+branch_labels = None
+depends_on = None
+
+
+def upgrade() -> None:
+    # A theme is a stored search: q plus the browse parameters it runs with.
+    # params mirrors ThemeParams in backend/golang/queries/themes.go
+    op.execute("""
+        CREATE TABLE themes (
+            id SERIAL PRIMARY KEY,
+            slug VARCHAR(100) NOT NULL,
+            title VARCHAR(200) NOT NULL,
+            description TEXT,
+            q TEXT NOT NULL DEFAULT '',
+            params JSONB NOT NULL DEFAULT '{}',
+            created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+            updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+            CONSTRAINT uq_themes_slug UNIQUE (slug)
+        )
+    """)
+
+    # Collections snapshotted from a theme remember where they came from
+    op.execute("""
+        ALTER TABLE collections
+            ADD COLUMN theme_id INTEGER REFERENCES themes(id) ON DELETE SET NULL
+    """)
+
+
+def downgrade() -> None:
+    # Drop themes and the snapshot link
+    op.execute("ALTER TABLE collections DROP COLUMN IF EXISTS theme_id")
+    op.execute("DROP TABLE IF EXISTS themes")
@@ -0,0 +1,38 @@
+"""add quote of the day picks
+
+Revision ID: 7f2c4a9e1b58
+Revises: c51a8e7d4f06
+Create Date: 2026-10-17 12:30:00.000000
+
+"""
+from alembic import op
+import sqlalchemy as sa
+
+
+# revision identifiers, used by Alembic.
+revision = '7f2c4a9e1b58'
+down_revision = 'c51a8e7d4f06'
+branch_labels = None
+depends_on = None
+
+
+def upgrade() -> None:
+    # One pick per date and filter set. filter_key is the encoded filters,
+    # e.g. "category=life&tag=love", or empty for the unfiltered pick.
+    # Deleting a quote drops its picks, so the day is picked again
+    op.execute("""
+        CREATE TABLE daily_quotes (
+            pick_date DATE NOT NULL,
+            filter_key VARCHAR(255) NOT NULL DEFAULT '',
+            quote_id INTEGER NOT NULL REFERENCES quotes(id) ON DELETE CASCADE,
+            picked_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+            PRIMARY KEY (filter_key, pick_date)
+        )
+    """)
+
+    op.execute("CREATE INDEX idx_daily_quotes_quote_id ON daily_quotes(quote_id)")
+
+
+def downgrade() -> None:
+    # Drop the picks
+    op.execute("DROP TABLE IF EXISTS daily_quotes")
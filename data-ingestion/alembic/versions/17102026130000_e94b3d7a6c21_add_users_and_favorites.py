@@ -0,0 +1,62 @@
+"""add users, favorites and personal collections
+
+Revision ID: e94b3d7a6c21
+Revises: 7f2c4a9e1b58
+Create Date: 2026-10-17 13:00:00.000000
+
+"""
+from alembic import op
+import sqlalchemy as sa
+
+
+# revision identifiers, used by Alembic.
+revision = 'e94b3d7a6c21'
+down_revision = '7f2c4a9e1b58'
+branch_labels = None
+depends_on = None
+
+
+def upgrade() -> None:
+    # Users authenticate with an API key; only its SHA-256 hex digest is stored
+    op.execute("""
+        CREATE TABLE users (
+            id SERIAL PRIMARY KEY,
+            name VARCHAR(100) NOT NULL,
+            api_key_hash CHAR(64) NOT NULL,
+            created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+            CONSTRAINT uq_users_api_key_hash UNIQUE (api_key_hash)
+        )
+    """)
+
+    op.execute("""
+        CREATE TABLE favorites (
+            user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+            quote_id INTEGER NOT NULL REFERENCES quotes(id) ON DELETE CASCADE,
+            created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+            PRIMARY KEY (user_id, quote_id)
+        )
+    """)
+
+    op.execute("CREATE INDEX idx_favorites_quote_id ON favorites(quote_id)")
+
+    # Personal collections belong to a user; editorial ones have no owner.
+    # Slugs are unique per owner, and among editorial collections
+    op.execute("""
+        ALTER TABLE collections
+            ADD COLUMN owner_id INTEGER REFERENCES users(id) ON DELETE CASCADE
+    """)
+    op.execute("ALTER TABLE collections DROP CONSTRAINT uq_collections_slug")
+    op.execute("""
+        ALTER TABLE collections
+            ADD CONSTRAINT uq_collections_owner_slug UNIQUE NULLS NOT DISTINCT (owner_id, slug)
+    """)
+
+
+def downgrade() -> None:
+    # Personal collections cannot keep their slugs unique without an owner
+    op.execute("DELETE FROM collections WHERE owner_id IS NOT NULL")
+    op.execute("ALTER TABLE collections DROP CONSTRAINT IF EXISTS uq_collections_owner_slug")
+    op.execute("ALTER TABLE collections ADD CONSTRAINT uq_collections_slug UNIQUE (slug)")
+    op.execute("ALTER TABLE collections DROP COLUMN IF EXISTS owner_id")
+    op.execute("DROP TABLE IF EXISTS favorites")
+    op.execute("DROP TABLE IF EXISTS users")
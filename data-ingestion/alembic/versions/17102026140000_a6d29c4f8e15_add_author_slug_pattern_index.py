@@ -0,0 +1,31 @@
+"""add author slug pattern index
+
+Revision ID: a6d29c4f8e15
+Revises: e94b3d7a6c21
+Create Date: 2026-10-17 14:00:00.000000
+
+"""
+from alembic import op
+import sqlalchemy as sa
+
+
+# revision identifiers, used by Alembic.
+revision = 'a6d29c4f8e15'
+down_revision = 'e94b3d7a6c21'
+branch_labels = None
+depends_on = None
+
+
+def upgrade() -> None:
+    # Serve the author directory's slug prefix filter (LIKE 'prefix%'), which
+    # idx_quotes_author_slug cannot under a non-C collation.
+    # The expression must match authorSlugSQL in backend/golang/queries/authors.go
+    op.execute("""
+        CREATE INDEX idx_quotes_author_slug_pattern ON quotes
+        ((trim(both '-' from regexp_replace(lower(author), '[^[:alnum:]]+', '-', 'g'))) text_pattern_ops)
+    """)
+
+
+def downgrade() -> None:
+    # Drop author slug pattern index
+    op.execute("DROP INDEX IF EXISTS idx_quotes_author_slug_pattern")